@@ -2,7 +2,6 @@ package builder
 
 import (
 	"errors"
-	"fmt"
 	"regexp"
 	"strings"
 )
@@ -93,11 +92,47 @@ func IsContainsCmd[T Cmd](src string, substrs ...T) bool {
 	return false
 }
 
-func BuildMigrationData(lines []string) ([]string, []string) {
-	upLines, downLines := make([]string, 0, len(lines)/2), make([]string, 0, len(lines)/2)
+type Section struct {
+	Lines []string
+	// LineNumbers are the numbers of Lines in the source file, counting
+	// from 1, nil if they aren't read from it.
+	LineNumbers []int
+	Transaction bool
+	// Present is false if the section marker wasn't found in the source.
+	Present bool
+}
+
+type MigrationData struct {
+	Up   Section
+	Down Section
+}
+
+type Migration struct {
+	// Filename is the source file the migration is read from.
+	Filename string
+	Version  int64
+	Name     string
+	Data     MigrationData
+	Format   SourceFormat
+	// Diagnostics are raised while reading the migration.
+	Diagnostics Diagnostics
+	// Dir is the name of the source folder, set if there are several.
+	Dir string
+}
+
+func ParseMigrationData(lines []string) MigrationData {
+	return ParseNumberedMigrationData(lines, nil)
+}
+
+// ParseNumberedMigrationData is ParseMigrationData keeping the numbers of
+// the section lines in the source file, numbers are those of lines.
+func ParseNumberedMigrationData(lines []string, numbers []int) MigrationData {
+	data := MigrationData{
+		Up:   Section{Lines: make([]string, 0, len(lines)/2)},
+		Down: Section{Lines: make([]string, 0, len(lines)/2)},
+	}
 	isUpTx := true
-	upTransactionMode, downTransactionMode := false, false
-	for _, line := range lines {
+	for i, line := range lines {
 		upMigrationLine := IsContainsCmd(line,
 			string(SqlMigrateCmdMigrationUp),
 			string(DbmateCmdMigrationUp),
@@ -110,39 +145,74 @@ func BuildMigrationData(lines []string) ([]string, []string) {
 		)
 		switch {
 		case upMigrationLine:
-			if !(IsContainsCmd(line, SqlMigrateCmdNoTransaction) ||
-				IsContainsCmd(line, DbmateCmdNoTransaction) ||
-				IsContainsCmd(line, GooseCmdNoTransaction)) {
-				upTransactionMode = true
-				upLines = append(upLines, "BEGIN;\n")
-			}
+			data.Up.Present = true
+			data.Up.Transaction = !isNoTransactionLine(line)
 			isUpTx = true
 		case downMigrationLine:
-			if upTransactionMode {
-				upLines = append(upLines, "COMMIT;\n")
-			}
-			if !(IsContainsCmd(line, SqlMigrateCmdNoTransaction) ||
-				IsContainsCmd(line, DbmateCmdNoTransaction) ||
-				IsContainsCmd(line, GooseCmdNoTransaction)) {
-				downTransactionMode = true
-				downLines = append(downLines, "BEGIN;\n")
-			}
+			data.Down.Present = true
+			data.Down.Transaction = !isNoTransactionLine(line)
 			isUpTx = false
 		case IsContainsCmd(line, SqlMigrateCmdStatementBegin) || IsContainsCmd(line, SqlMigrateCmdStatementEnd) ||
 			IsContainsCmd(line, GooseCmdStatementBegin) || IsContainsCmd(line, GooseCmdStatementEnd):
-			fmt.Printf("skip line %s", line)
+			// statements are split by the destination library
 		default:
+			section := &data.Down
 			if isUpTx {
-				upLines = append(upLines, line)
-			} else {
-				downLines = append(downLines, line)
+				section = &data.Up
+			}
+			section.Lines = append(section.Lines, line)
+			if numbers != nil {
+				section.LineNumbers = append(section.LineNumbers, numbers[i])
 			}
-
 		}
 	}
-	if downTransactionMode {
-		downLines = append(downLines, "\nCOMMIT;\n")
+	return data
+}
+
+// StatementLineNumbers returns the source line where each statement of the
+// section starts, nil if the line numbers are unknown.
+func (s Section) StatementLineNumbers() []int {
+	if s.LineNumbers == nil {
+		return nil
+	}
+	starts := StatementLines(s.Lines)
+	numbers := make([]int, 0, len(starts))
+	for _, start := range starts {
+		numbers = append(numbers, s.LineNumbers[start-1])
 	}
+	return numbers
+}
+
+func isNoTransactionLine(line string) bool {
+	return IsContainsCmd(line, SqlMigrateCmdNoTransaction) ||
+		IsContainsCmd(line, DbmateCmdNoTransaction) ||
+		IsContainsCmd(line, GooseCmdNoTransaction)
+}
+
+func (d MigrationData) Build() ([]string, []string) {
+	return d.Up.build("COMMIT;\n"), d.Down.build("\nCOMMIT;\n")
+}
 
-	return upLines, downLines
+func (s Section) build(commit string) []string {
+	if !s.Transaction {
+		return s.Lines
+	}
+	result := make([]string, 0, len(s.Lines)+2)
+	result = append(result, "BEGIN;\n")
+	result = append(result, s.Lines...)
+	result = append(result, commit)
+	return result
+}
+
+func BuildMigrationData(lines []string) ([]string, []string) {
+	return ParseMigrationData(lines).Build()
+}
+
+// NextVersion keeps versions unique and strictly increasing: a version that
+// is not greater than the last used one is bumped right after it.
+func NextVersion(version, maxVersion int64) int64 {
+	if version <= maxVersion {
+		return maxVersion + 1
+	}
+	return version
 }
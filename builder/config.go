@@ -0,0 +1,109 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFilename is the config file read from the working directory.
+const ConfigFilename = "migradaptor.yaml"
+
+// Config holds the options of the commands, the keys are the flag names.
+// Empty values keep the flag defaults.
+type Config struct {
+	Src             []string          `yaml:"src"`
+	Dst             string            `yaml:"dst"`
+	DstLib          string            `yaml:"dst-lib"`
+	Placeholders    string            `yaml:"placeholders"`
+	VarsFile        string            `yaml:"vars-file"`
+	Vars            map[string]string `yaml:"vars"`
+	GenDown         bool              `yaml:"gen-down"`
+	Versioning      string            `yaml:"versioning"`
+	GitVersions     bool              `yaml:"git-versions"`
+	Recursive       bool              `yaml:"recursive"`
+	Include         []string          `yaml:"include"`
+	Exclude         []string          `yaml:"exclude"`
+	ContinueOnError bool              `yaml:"continue-on-error"`
+	WriteValid      bool              `yaml:"write-valid"`
+	Diagnostics     string            `yaml:"diagnostics"`
+	Report          string            `yaml:"report"`
+	Lint            LintConfig        `yaml:"lint"`
+	Stamp           StampConfig       `yaml:"stamp"`
+	Squash          SquashConfig      `yaml:"squash"`
+}
+
+type LintConfig struct {
+	FailOn string `yaml:"fail-on"`
+	// Rules maps the rules to their severities.
+	Rules map[string]string `yaml:"rules"`
+}
+
+type StampConfig struct {
+	From    string `yaml:"from"`
+	Dialect string `yaml:"dialect"`
+	Table   string `yaml:"table"`
+	Out     string `yaml:"out"`
+}
+
+type SquashConfig struct {
+	From int64  `yaml:"from"`
+	To   int64  `yaml:"to"`
+	Name string `yaml:"name"`
+}
+
+// ReadConfig decodes the config, unknown keys are errors.
+func ReadConfig(r io.Reader) (Config, error) {
+	var c Config
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return c, fmt.Errorf("%w: %s", ErrInvalidConfig, err.Error())
+	}
+	return c, nil
+}
+
+// Validate returns all the invalid values joined.
+func (c Config) Validate() error {
+	var errJoin error
+	check := func(key, value string, get func(string) error) {
+		if value == "" {
+			return
+		}
+		if err := get(value); err != nil {
+			errJoin = errors.Join(errJoin, fmt.Errorf("%w: %s: %q", err, key, value))
+		}
+	}
+	check("dst-lib", c.DstLib, func(v string) (err error) { _, err = GetDstType(v); return })
+	check("placeholders", c.Placeholders, func(v string) (err error) { _, err = GetPlaceholderMode(v); return })
+	check("versioning", c.Versioning, func(v string) (err error) { _, err = GetVersioning(v); return })
+	check("diagnostics", c.Diagnostics, func(v string) (err error) { _, err = GetReportFormat(v); return })
+	check("lint.fail-on", c.Lint.FailOn, func(v string) (err error) { _, err = GetSeverity(v); return })
+	check("stamp.from", c.Stamp.From, func(v string) (err error) { _, err = GetHistorySource(v); return })
+	check("stamp.dialect", c.Stamp.Dialect, func(v string) (err error) { _, err = GetDialect(v); return })
+	rules := make([]string, 0, len(c.Lint.Rules))
+	for rule := range c.Lint.Rules {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		if _, _, err := ParseLintRuleSeverity(rule + "=" + c.Lint.Rules[rule]); err != nil {
+			errJoin = errors.Join(errJoin, fmt.Errorf("lint.rules: %w", err))
+		}
+	}
+	for _, src := range c.Src {
+		if src == "" {
+			errJoin = errors.Join(errJoin, fmt.Errorf("%w: src", ErrNoSrcFolderPath))
+		}
+		if src == c.Dst {
+			errJoin = errors.Join(errJoin, fmt.Errorf("%w: %s", ErrLegacyAndDestEqual, src))
+		}
+	}
+	if c.Squash.From > c.Squash.To {
+		errJoin = errors.Join(errJoin, fmt.Errorf("%w: squash.from %d is greater than squash.to %d", ErrInvalidConfig, c.Squash.From, c.Squash.To))
+	}
+	return errJoin
+}
@@ -0,0 +1,69 @@
+package builder_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func TestReadConfig(t *testing.T) {
+	t.Parallel()
+	config, err := builder.ReadConfig(strings.NewReader(`
+src: [db/migrations, billing=db/billing]
+dst: migrations
+versioning: timestamp
+recursive: true
+vars:
+  SCHEMA: public
+lint:
+  fail-on: warning
+  rules:
+    missing-down: error
+stamp:
+  from: goose
+`))
+	require.NoError(t, err)
+	require.Equal(t, builder.Config{
+		Src:        []string{"db/migrations", "billing=db/billing"},
+		Dst:        "migrations",
+		Versioning: "timestamp",
+		Recursive:  true,
+		Vars:       map[string]string{"SCHEMA": "public"},
+		Lint:       builder.LintConfig{FailOn: "warning", Rules: map[string]string{"missing-down": "error"}},
+		Stamp:      builder.StampConfig{From: "goose"},
+	}, config)
+	require.NoError(t, config.Validate())
+
+	config, err = builder.ReadConfig(strings.NewReader(""))
+	require.NoError(t, err)
+	require.Equal(t, builder.Config{}, config)
+
+	_, err = builder.ReadConfig(strings.NewReader("versionning: timestamp\n"))
+	require.ErrorIs(t, err, builder.ErrInvalidConfig)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+	config := builder.Config{
+		Src:        []string{"migrations"},
+		Dst:        "migrations",
+		Versioning: "semver",
+		Lint:       builder.LintConfig{Rules: map[string]string{"missing-up": "error"}},
+		Stamp:      builder.StampConfig{Dialect: "oracle"},
+		Squash:     builder.SquashConfig{From: 2, To: 1},
+	}
+	err := config.Validate()
+	for _, expected := range []error{
+		builder.ErrUnknownVersioning,
+		builder.ErrUnknownLintRule,
+		builder.ErrUnknownDialect,
+		builder.ErrLegacyAndDestEqual,
+		builder.ErrInvalidConfig,
+	} {
+		require.True(t, errors.Is(err, expected), expected.Error())
+	}
+}
@@ -0,0 +1,43 @@
+package builder
+
+import "strings"
+
+// SourceFormat is the library a source migration file is written for.
+type SourceFormat string
+
+var (
+	SourceFormatGoose      SourceFormat = "goose"
+	SourceFormatGooseGo    SourceFormat = "goose-go"
+	SourceFormatSqlMigrate SourceFormat = "sql-migrate"
+	SourceFormatDbmate     SourceFormat = "dbmate"
+	SourceFormatGoPg       SourceFormat = "go-pg"
+	// SourceFormatPlain is a sql file without the annotations of any library.
+	SourceFormatPlain SourceFormat = "plain"
+)
+
+func GetSourceFormat(format string) (SourceFormat, error) {
+	format = strings.TrimSpace(format)
+	format = strings.ToLower(format)
+	switch SourceFormat(format) {
+	case SourceFormatGoose, SourceFormatGooseGo, SourceFormatSqlMigrate, SourceFormatDbmate, SourceFormatGoPg, SourceFormatPlain:
+		return SourceFormat(format), nil
+	default:
+		return *(new(SourceFormat)), ErrUnknownSourceFormat
+	}
+}
+
+// DetectSourceFormat returns the format of a sql migration by the first
+// up or down annotation.
+func DetectSourceFormat(lines []string) SourceFormat {
+	for _, line := range lines {
+		switch {
+		case IsContainsCmd(line, GooseCmdMigrationUp, GooseCmdMigrationDown):
+			return SourceFormatGoose
+		case IsContainsCmd(line, SqlMigrateCmdMigrationUp, SqlMigrateCmdMigrationDown):
+			return SourceFormatSqlMigrate
+		case IsContainsCmd(line, DbmateCmdMigrationUp, DbmateCmdMigrationDown):
+			return SourceFormatDbmate
+		}
+	}
+	return SourceFormatPlain
+}
@@ -0,0 +1,36 @@
+package builder_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func TestDetectSourceFormat(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		input    string
+		expected builder.SourceFormat
+	}{
+		{"-- +goose Up\nCREATE TABLE t (id int);", builder.SourceFormatGoose},
+		{"-- comment\n-- +migrate Up notransaction\nCREATE INDEX CONCURRENTLY i ON t (id);", builder.SourceFormatSqlMigrate},
+		{"-- migrate:up\nCREATE TABLE t (id int);\n-- migrate:down", builder.SourceFormatDbmate},
+		{"CREATE TABLE t (id int);", builder.SourceFormatPlain},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, builder.DetectSourceFormat(strings.Split(tc.input, "\n")), tc.input)
+	}
+}
+
+func TestGetSourceFormat(t *testing.T) {
+	t.Parallel()
+	format, err := builder.GetSourceFormat(" Goose-Go ")
+	require.NoError(t, err)
+	require.Equal(t, builder.SourceFormatGooseGo, format)
+
+	_, err = builder.GetSourceFormat("flyway")
+	require.ErrorIs(t, err, builder.ErrUnknownSourceFormat)
+}
@@ -0,0 +1,248 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type DiagnosticCode string
+
+var (
+	DiagnosticUnresolvedPlaceholder DiagnosticCode = "unresolved-placeholder"
+	DiagnosticGooseGoLogic          DiagnosticCode = "goose-go-logic"
+	DiagnosticGoPgGoOnly            DiagnosticCode = "go-pg-go-only"
+	DiagnosticGeneratedDown         DiagnosticCode = "generated-down"
+	DiagnosticIrreversible          DiagnosticCode = "irreversible-statement"
+	DiagnosticVersionChanged        DiagnosticCode = "version-changed"
+	DiagnosticSkipped               DiagnosticCode = "skipped"
+	DiagnosticConverted             DiagnosticCode = "converted"
+	DiagnosticReadFailed            DiagnosticCode = "read-failed"
+	DiagnosticInvalidFilename       DiagnosticCode = "invalid-filename"
+	DiagnosticInvalidMigration      DiagnosticCode = "invalid-migration"
+	DiagnosticWriteFailed           DiagnosticCode = "write-failed"
+)
+
+// Diagnostic is a message about a source or destination file. Line and
+// Column start from 1 and are 0 if unknown. It's an error, so the errors of
+// a file can be returned as diagnostics.
+type Diagnostic struct {
+	Severity Severity       `json:"severity"`
+	Code     DiagnosticCode `json:"code,omitempty"`
+	File     string         `json:"file,omitempty"`
+	Line     int            `json:"line,omitempty"`
+	Column   int            `json:"column,omitempty"`
+	Message  string         `json:"message"`
+	// Err is the error the diagnostic is raised for.
+	Err error `json:"-"`
+}
+
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+	if d.File != "" {
+		sb.WriteString(d.File)
+		if d.Line != 0 {
+			sb.WriteString(fmt.Sprintf(":%d", d.Line))
+			if d.Column != 0 {
+				sb.WriteString(fmt.Sprintf(":%d", d.Column))
+			}
+		}
+		sb.WriteString(": ")
+	}
+	sb.WriteString(string(d.Severity))
+	sb.WriteString(": ")
+	if d.Code != "" {
+		sb.WriteString(string(d.Code))
+		sb.WriteString(": ")
+	}
+	sb.WriteString(d.Message)
+	return sb.String()
+}
+
+func (d Diagnostic) Error() string {
+	return d.String()
+}
+
+func (d Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// FileError returns an error diagnostic for the file.
+func FileError(code DiagnosticCode, file string, err error) Diagnostic {
+	return Diagnostic{Severity: SeverityError, Code: code, File: file, Message: err.Error(), Err: err}
+}
+
+// Diagnostics collects the diagnostics raised while parsing and writing.
+type Diagnostics []Diagnostic
+
+func (d *Diagnostics) Add(severity Severity, code DiagnosticCode, file string, line int, format string, args ...any) {
+	*d = append(*d, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		File:     file,
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Messages returns the messages of the diagnostics of the given severity, nil
+// if there are none.
+func (d Diagnostics) Messages(severity Severity) []string {
+	var result []string
+	for _, diagnostic := range d {
+		if diagnostic.Severity == severity {
+			result = append(result, diagnostic.Message)
+		}
+	}
+	return result
+}
+
+// Failures returns the error diagnostics.
+func (d Diagnostics) Failures() Diagnostics {
+	var result Diagnostics
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			result = append(result, diagnostic)
+		}
+	}
+	return result
+}
+
+// Err joins the diagnostics into an ErrFilesFailed error, nil if there are none.
+func (d Diagnostics) Err() error {
+	if len(d) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(d))
+	for _, diagnostic := range d {
+		errs = append(errs, diagnostic)
+	}
+	return fmt.Errorf("%w: %w", ErrFilesFailed, errors.Join(errs...))
+}
+
+// Reporter prints the diagnostics in some format.
+type Reporter interface {
+	Report(d Diagnostic)
+	// Close flushes the reported diagnostics.
+	Close() error
+}
+
+type ReportFormat string
+
+var (
+	ReportFormatText   ReportFormat = "text"
+	ReportFormatJSON   ReportFormat = "json"
+	ReportFormatGitHub ReportFormat = "github"
+)
+
+func GetReportFormat(format string) (ReportFormat, error) {
+	format = strings.TrimSpace(format)
+	format = strings.ToLower(format)
+	switch ReportFormat(format) {
+	case ReportFormatText, ReportFormatJSON, ReportFormatGitHub:
+		return ReportFormat(format), nil
+	default:
+		return *(new(ReportFormat)), ErrUnknownReportFormat
+	}
+}
+
+// NewReporter returns a text reporter writing a line per diagnostic, a JSON
+// one writing an array on Close or a GitHub Actions one writing workflow
+// annotation commands.
+func NewReporter(format ReportFormat, w io.Writer) Reporter {
+	switch format {
+	case ReportFormatJSON:
+		return &jsonReporter{w: w, diagnostics: make([]Diagnostic, 0)}
+	case ReportFormatGitHub:
+		return &githubReporter{w: w}
+	default:
+		return &textReporter{w: w}
+	}
+}
+
+type textReporter struct {
+	w   io.Writer
+	err error
+}
+
+func (r *textReporter) Report(d Diagnostic) {
+	if _, err := fmt.Fprintln(r.w, d.String()); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *textReporter) Close() error {
+	return r.err
+}
+
+type jsonReporter struct {
+	w           io.Writer
+	diagnostics []Diagnostic
+}
+
+func (r *jsonReporter) Report(d Diagnostic) {
+	r.diagnostics = append(r.diagnostics, d)
+}
+
+func (r *jsonReporter) Close() error {
+	content, err := json.MarshalIndent(r.diagnostics, "", "  ")
+	if err != nil {
+		return err
+	}
+	r.diagnostics = r.diagnostics[:0]
+	_, err = r.w.Write(append(content, '\n'))
+	return err
+}
+
+type githubReporter struct {
+	w   io.Writer
+	err error
+}
+
+var githubCommands = map[Severity]string{
+	SeverityInfo:    "notice",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (r *githubReporter) Report(d Diagnostic) {
+	command, ok := githubCommands[d.Severity]
+	if !ok {
+		return
+	}
+	properties := make([]string, 0, 4)
+	if d.File != "" {
+		properties = append(properties, "file="+escapeGitHubProperty(d.File))
+	}
+	if d.Line != 0 {
+		properties = append(properties, fmt.Sprintf("line=%d", d.Line))
+	}
+	if d.Column != 0 {
+		properties = append(properties, fmt.Sprintf("col=%d", d.Column))
+	}
+	if d.Code != "" {
+		properties = append(properties, "title="+escapeGitHubProperty(string(d.Code)))
+	}
+	line := "::" + command
+	if len(properties) != 0 {
+		line += " " + strings.Join(properties, ",")
+	}
+	line += "::" + escapeGitHubData(d.Message)
+	if _, err := fmt.Fprintln(r.w, line); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *githubReporter) Close() error {
+	return r.err
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,73 @@
+package builder_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func TestReporter(t *testing.T) {
+	t.Parallel()
+	diags := builder.Diagnostics{
+		{Severity: builder.SeverityWarning, Code: builder.DiagnosticUnresolvedPlaceholder, File: "1-users.sql", Line: 3, Message: "unresolved placeholder ${X}"},
+		{Severity: builder.SeverityError, Message: "a, b: 100%\nfailed"},
+	}
+	testCases := []struct {
+		format   builder.ReportFormat
+		expected string
+	}{
+		{
+			format: builder.ReportFormatText,
+			expected: "1-users.sql:3: warning: unresolved-placeholder: unresolved placeholder ${X}\n" +
+				"error: a, b: 100%\nfailed\n",
+		},
+		{
+			format: builder.ReportFormatGitHub,
+			expected: "::warning file=1-users.sql,line=3,title=unresolved-placeholder::unresolved placeholder ${X}\n" +
+				"::error::a, b: 100%25%0Afailed\n",
+		},
+		{
+			format: builder.ReportFormatJSON,
+			expected: `[
+  {
+    "severity": "warning",
+    "code": "unresolved-placeholder",
+    "file": "1-users.sql",
+    "line": 3,
+    "message": "unresolved placeholder ${X}"
+  },
+  {
+    "severity": "error",
+    "message": "a, b: 100%\nfailed"
+  }
+]
+`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(string(tc.format), func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			reporter := builder.NewReporter(tc.format, &buf)
+			for _, d := range diags {
+				reporter.Report(d)
+			}
+			require.NoError(t, reporter.Close())
+			require.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestGetReportFormat(t *testing.T) {
+	t.Parallel()
+	format, err := builder.GetReportFormat(" GitHub ")
+	require.NoError(t, err)
+	require.Equal(t, builder.ReportFormatGitHub, format)
+
+	_, err = builder.GetReportFormat("xml")
+	require.ErrorIs(t, err, builder.ErrUnknownReportFormat)
+}
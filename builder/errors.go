@@ -3,9 +3,29 @@ package builder
 import "github.com/pkg/errors"
 
 var (
-	ErrUnknownSourceType  = errors.New("unknown source type")
-	ErrNoDstTypeProvided  = errors.New("no source type provided")
-	ErrNoSrcFolderPath    = errors.New("no dst folder path provided")
-	ErrNoDstFolderPath    = errors.New("no dst folder path provided")
-	ErrLegacyAndDestEqual = errors.New("src and dst path are equal")
+	ErrUnknownSourceType      = errors.New("unknown source type")
+	ErrNoDstTypeProvided      = errors.New("no source type provided")
+	ErrNoSrcFolderPath        = errors.New("no dst folder path provided")
+	ErrNoDstFolderPath        = errors.New("no dst folder path provided")
+	ErrLegacyAndDestEqual     = errors.New("src and dst path are equal")
+	ErrGoPgNameMismatch       = errors.New("go-pg migration name mismatch")
+	ErrGoPgDuplicateFile      = errors.New("duplicate go-pg migration file")
+	ErrUnknownPlaceholderMode = errors.New("unknown placeholder mode")
+	ErrInvalidVarsFile        = errors.New("invalid vars file")
+	ErrUnknownSeverity        = errors.New("unknown severity")
+	ErrUnknownLintRule        = errors.New("unknown lint rule")
+	ErrInvalidManifest        = errors.New("invalid manifest")
+	ErrUnknownDialect         = errors.New("unknown dialect")
+	ErrUnknownHistorySource   = errors.New("unknown history table source")
+	ErrUnknownVersioning      = errors.New("unknown versioning")
+	ErrVersionCollision       = errors.New("versions are not strictly increasing")
+	ErrDuplicateSourceName    = errors.New("duplicate source name")
+	ErrEmptySquashRange       = errors.New("no migrations in squash range")
+	ErrUnknownReportFormat    = errors.New("unknown report format")
+	ErrInternal               = errors.New("internal error")
+	ErrFilesFailed            = errors.New("some files failed")
+	ErrInvalidConfig          = errors.New("invalid config")
+	ErrInvalidVersion         = errors.New("invalid version")
+	ErrNoMigrationName        = errors.New("no migration name provided")
+	ErrUnknownSourceFormat    = errors.New("unknown source format")
 )
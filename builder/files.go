@@ -3,6 +3,8 @@ package builder
 import (
 	"bufio"
 	"bytes"
+	"io"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
@@ -27,15 +29,27 @@ func RemoveContents(dir string) error {
 	return nil
 }
 
-func ReadFileLines(f *os.File) ([]string, error) {
+func ReadFileLines(r io.Reader) ([]string, error) {
 	result := make([]string, 0)
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		result = append(result, scanner.Text())
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
+func ReadFSFileLines(fsys fs.FS, name string) ([]string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadFileLines(f)
+}
+
 func BuildBuffer(lines []string) []byte {
 	var buffer bytes.Buffer
 	for _, line := range lines {
@@ -48,11 +62,15 @@ func BuildBuffer(lines []string) []byte {
 func CreateAndWrite(pth, filename string, lines []string) error {
 	fup, err := os.Create(path.Join(pth, filename))
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer fup.Close()
 	if _, err := fup.Write(BuildBuffer(lines)); err != nil {
-		panic(err)
+		_ = fup.Close()
+		return err
 	}
-	return nil
+	return fup.Close()
+}
+
+func WriteFile(pth string, f File) error {
+	return os.WriteFile(path.Join(pth, f.Name), f.Content, 0o644)
 }
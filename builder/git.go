@@ -0,0 +1,48 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitVersions returns the UTC time of the commit that first added each file
+// of dir as a YYYYMMDDHHMMSS version, keyed by the path relative to dir.
+// Renames are not followed, a renamed file gets the time of the rename.
+func GitVersions(dir string) (map[string]int64, error) {
+	cmd := exec.Command("git", "-C", dir, "log", "--reverse", "--no-renames", "--diff-filter=A",
+		"--format=%x00%ct", "--name-only", "--relative", "--", ".")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	versions := make(map[string]int64)
+	for _, commit := range strings.Split(string(out), "\x00") {
+		lines := strings.Split(strings.TrimSpace(commit), "\n")
+		if len(lines) < 2 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(lines[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("git log: parse commit time %s: %w", lines[0], err)
+		}
+		version, err := strconv.ParseInt(time.Unix(seconds, 0).UTC().Format(timestampVersionLayout), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range lines[1:] {
+			name = strings.TrimSpace(name)
+			if _, ok := versions[name]; name == "" || ok {
+				continue
+			}
+			versions[name] = version
+		}
+	}
+	return versions, nil
+}
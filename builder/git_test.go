@@ -0,0 +1,54 @@
+package builder_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func TestGitVersions(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(date string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+		)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), os.ModePerm))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	git("", "init", "-q")
+	write("migrations/1-indexes.sql", "-- +migrate Up\n")
+	git("2023-01-02T10:00:00Z", "add", ".")
+	git("2023-01-02T10:00:00Z", "commit", "-q", "-m", "indexes")
+	write("migrations/2-companies.sql", "-- +migrate Up\n")
+	write("migrations/1-indexes.sql", "-- +migrate Up\nSELECT 1;\n")
+	git("2023-03-04T12:30:15+02:00", "add", ".")
+	git("2023-03-04T12:30:15+02:00", "commit", "-q", "-m", "companies")
+	write("migrations/3-users.sql", "-- +migrate Up\n")
+
+	versions, err := builder.GitVersions(filepath.Join(dir, "migrations"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{
+		"1-indexes.sql":   20230102100000,
+		"2-companies.sql": 20230304103015,
+	}, versions)
+
+	_, err = builder.GitVersions(t.TempDir())
+	require.Error(t, err)
+}
@@ -0,0 +1,306 @@
+package builder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	goFilenameReg      = regexp.MustCompile(`^(\d+)_(.+)\.go$`)
+	gooseRegisterFuncs = map[string]struct {
+		noTx  bool
+		named bool
+	}{
+		"AddMigration":                 {},
+		"AddMigrationContext":          {},
+		"AddMigrationNoTx":             {noTx: true},
+		"AddMigrationNoTxContext":      {noTx: true},
+		"AddNamedMigration":            {named: true},
+		"AddNamedMigrationContext":     {named: true},
+		"AddNamedMigrationNoTx":        {noTx: true, named: true},
+		"AddNamedMigrationNoTxContext": {noTx: true, named: true},
+	}
+	// method name -> index of the sql argument
+	gooseExecFuncs = map[string]int{
+		"Exec":        0,
+		"ExecContext": 1,
+	}
+	// error constructors and wrappers, their arguments are extracted
+	gooseErrorFuncs = map[string]struct{}{
+		"fmt.Errorf":          {},
+		"errors.New":          {},
+		"errors.Errorf":       {},
+		"errors.Join":         {},
+		"errors.Wrap":         {},
+		"errors.Wrapf":        {},
+		"errors.WithMessage":  {},
+		"errors.WithMessagef": {},
+		"errors.WithStack":    {},
+	}
+)
+
+type GooseGoMigration struct {
+	// Registered is false if the file doesn't call goose.AddMigration*.
+	Registered bool
+	NoTx       bool
+	Up         []string
+	Down       []string
+	// Warnings lists the functions whose logic can't be extracted statically,
+	// a NotExtractedComment marks where it's missing in Up and Down.
+	Warnings []GooseGoWarning
+}
+
+// GooseGoWarning is a warning about the go code at Position.
+type GooseGoWarning struct {
+	Position token.Position
+	Message  string
+}
+
+func (w GooseGoWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Position, w.Message)
+}
+
+// NotExtractedComment prefixes the comment written in place of the logic
+// that can't be extracted statically, so the generated sql isn't mistaken
+// for the whole migration.
+const NotExtractedComment = "-- migradaptor: not extracted: "
+
+func IsGoMigrationFile(filename string) bool {
+	return isKeyExists(goFilenameReg, filename) && !strings.HasSuffix(filename, "_test.go")
+}
+
+func ParseGoFilename(filename string) (int64, string, error) {
+	fileparts := goFilenameReg.FindStringSubmatch(filename)
+	if fileparts == nil {
+		return 0, "", fmt.Errorf("parse fileparts: filename %s not match", filename)
+	}
+	// 1 - version, 2 - name
+	ts, err := strconv.ParseInt(fileparts[1], 10, 64)
+	if err != nil {
+		return 0, "", errors.Wrap(err, "parse timestamp")
+	}
+	return ts, fileparts[2], nil
+}
+
+// ParseGooseGoMigration finds the up and down functions registered with
+// goose.AddMigration* and extracts the constant SQL passed to Exec/ExecContext.
+func ParseGooseGoMigration(filename string, src []byte) (GooseGoMigration, error) {
+	var result GooseGoMigration
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return result, errors.Wrap(err, "parse go file")
+	}
+
+	funcs := make(map[string]*ast.FuncDecl)
+	consts := make(map[string]ast.Expr)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				funcs[d.Name.Name] = d
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range d.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i < len(vs.Values) {
+						consts[name.Name] = vs.Values[i]
+					}
+				}
+			}
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || result.Registered {
+			return !result.Registered
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok || pkg.Name != "goose" {
+			return true
+		}
+		register, ok := gooseRegisterFuncs[sel.Sel.Name]
+		if !ok {
+			return true
+		}
+		args := call.Args
+		if register.named && len(args) > 0 {
+			args = args[1:]
+		}
+		if len(args) != 2 {
+			result.Warnings = append(result.Warnings, GooseGoWarning{
+				Position: fset.Position(call.Pos()),
+				Message:  fmt.Sprintf("goose.%s: unexpected number of arguments", sel.Sel.Name),
+			})
+			return false
+		}
+		result.Registered = true
+		result.NoTx = register.noTx
+		result.Up = extractGooseFunc(fset, args[0], funcs, consts, &result.Warnings)
+		result.Down = extractGooseFunc(fset, args[1], funcs, consts, &result.Warnings)
+		return false
+	})
+
+	return result, nil
+}
+
+func extractGooseFunc(
+	fset *token.FileSet,
+	arg ast.Expr,
+	funcs map[string]*ast.FuncDecl,
+	consts map[string]ast.Expr,
+	warnings *[]GooseGoWarning,
+) []string {
+	statements := make([]string, 0)
+	notExtracted := func(pos token.Pos, format string, args ...any) {
+		message := fmt.Sprintf(format, args...)
+		*warnings = append(*warnings, GooseGoWarning{Position: fset.Position(pos), Message: message})
+		statements = append(statements, NotExtractedComment+message)
+	}
+
+	var (
+		name string
+		body *ast.BlockStmt
+	)
+	switch fn := arg.(type) {
+	case *ast.Ident:
+		if fn.Name == "nil" {
+			return nil
+		}
+		decl, ok := funcs[fn.Name]
+		if !ok {
+			notExtracted(fn.Pos(), "function %s is not declared in this file", fn.Name)
+			return statements
+		}
+		name, body = fn.Name, decl.Body
+	case *ast.FuncLit:
+		name, body = "func literal", fn.Body
+	default:
+		notExtracted(arg.Pos(), "migration function can't be resolved statically")
+		return statements
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			notExtracted(node.Pos(), "%s: loops can't be extracted statically", name)
+			return false
+		case *ast.CallExpr:
+			sel, ok := node.Fun.(*ast.SelectorExpr)
+			if !ok {
+				notExtracted(node.Pos(), "%s: call can't be extracted statically", name)
+				return false
+			}
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				if _, ok := gooseErrorFuncs[pkg.Name+"."+sel.Sel.Name]; ok {
+					// error wrapping doesn't affect the schema, the calls in its arguments may
+					return true
+				}
+			}
+			argIdx, ok := gooseExecFuncs[sel.Sel.Name]
+			if !ok {
+				notExtracted(node.Pos(), "%s: call to %s can't be extracted statically", name, sel.Sel.Name)
+				return false
+			}
+			if len(node.Args) != argIdx+1 {
+				notExtracted(node.Pos(), "%s: %s with query arguments can't be extracted statically", name, sel.Sel.Name)
+				return false
+			}
+			sql, ok := evalConstString(node.Args[argIdx], consts)
+			if !ok {
+				notExtracted(node.Pos(), "%s: %s argument is not a constant string", name, sel.Sel.Name)
+				return false
+			}
+			if sql = strings.TrimSpace(sql); sql != "" {
+				statements = append(statements, sql)
+			}
+			return false
+		}
+		return true
+	})
+	return statements
+}
+
+func evalConstString(expr ast.Expr, consts map[string]ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.Ident:
+		value, ok := consts[e.Name]
+		if !ok {
+			return "", false
+		}
+		delete(consts, e.Name) // guard against self-referencing constants
+		defer func() { consts[e.Name] = value }()
+		return evalConstString(value, consts)
+	case *ast.ParenExpr:
+		return evalConstString(e.X, consts)
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		x, ok := evalConstString(e.X, consts)
+		if !ok {
+			return "", false
+		}
+		y, ok := evalConstString(e.Y, consts)
+		if !ok {
+			return "", false
+		}
+		return x + y, true
+	default:
+		return "", false
+	}
+}
+
+// Lines renders the migration as a goose sql file, so it can be passed
+// to BuildMigrationData as any other source.
+func (m GooseGoMigration) Lines() []string {
+	upCmd, downCmd := "-- "+string(GooseCmdMigrationUp), "-- "+string(GooseCmdMigrationDown)
+	if m.NoTx {
+		upCmd += " " + string(GooseCmdNoTransaction)
+		downCmd += " " + string(GooseCmdNoTransaction)
+	}
+	lines := []string{upCmd}
+	lines = append(lines, gooseStatementLines(m.Up)...)
+	lines = append(lines, downCmd)
+	lines = append(lines, gooseStatementLines(m.Down)...)
+	return lines
+}
+
+func gooseStatementLines(statements []string) []string {
+	lines := make([]string, 0, len(statements)*3)
+	for _, statement := range statements {
+		if strings.HasPrefix(statement, NotExtractedComment) {
+			lines = append(lines, statement)
+			continue
+		}
+		if !strings.HasSuffix(statement, ";") {
+			statement += ";"
+		}
+		lines = append(lines, "-- "+string(GooseCmdStatementBegin))
+		lines = append(lines, strings.Split(statement, "\n")...)
+		lines = append(lines, "-- "+string(GooseCmdStatementEnd))
+	}
+	return lines
+}
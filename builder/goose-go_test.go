@@ -0,0 +1,187 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func Test_ParseGooseGoMigration(t *testing.T) {
+	testCases := []struct {
+		name     string
+		src      string
+		expected builder.GooseGoMigration
+		warnings int
+	}{
+		{
+			"declared functions with constants",
+			`package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+const usersTable = "users"
+
+const createUsers = "CREATE TABLE " + usersTable + " (id int);"
+
+func init() {
+	goose.AddMigrationContext(upUsers, downUsers)
+}
+
+func upUsers(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, createUsers); err != nil {
+		return fmt.Errorf("create users: %w", err)
+	}
+	_, err := tx.ExecContext(ctx, ` + "`" + `
+		CREATE INDEX users_id_idx ON users (id);
+	` + "`" + `)
+	return err
+}
+
+func downUsers(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE users;")
+	return err
+}
+`,
+			builder.GooseGoMigration{
+				Registered: true,
+				Up:         []string{"CREATE TABLE users (id int);", "CREATE INDEX users_id_idx ON users (id);"},
+				Down:       []string{"DROP TABLE users;"},
+			},
+			0,
+		},
+		{
+			"func literals without transaction",
+			`package migrations
+
+func init() {
+	goose.AddNamedMigrationNoTx("00002_index.go", func(db *sql.DB) error {
+		_, err := db.Exec("CREATE INDEX CONCURRENTLY users_name_idx ON users (name)")
+		return err
+	}, nil)
+}
+`,
+			builder.GooseGoMigration{
+				Registered: true,
+				NoTx:       true,
+				Up:         []string{"CREATE INDEX CONCURRENTLY users_name_idx ON users (name)"},
+			},
+			0,
+		},
+		{
+			"logic that can't be extracted",
+			`package migrations
+
+func init() {
+	goose.AddMigration(upSeed, downSeed)
+}
+
+func upSeed(tx *sql.Tx) error {
+	for _, name := range names {
+		if _, err := tx.Exec("INSERT INTO users (name) VALUES ($1)", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downSeed(tx *sql.Tx) error {
+	_, err := tx.Exec(buildQuery())
+	return err
+}
+`,
+			builder.GooseGoMigration{
+				Registered: true,
+				Up:         []string{builder.NotExtractedComment + "upSeed: loops can't be extracted statically"},
+				Down:       []string{builder.NotExtractedComment + "downSeed: Exec argument is not a constant string"},
+			},
+			2,
+		},
+		{
+			"calls in return statements",
+			`package migrations
+
+func init() {
+	goose.AddMigration(upIdx, downIdx)
+}
+
+func upIdx(tx *sql.Tx) error {
+	return addIndex(tx, "users")
+}
+
+func downIdx(tx *sql.Tx) error {
+	return errors.Wrap(tx.Exec("DROP INDEX users_idx;"), "drop index")
+}
+`,
+			builder.GooseGoMigration{
+				Registered: true,
+				Up:         []string{builder.NotExtractedComment + "upIdx: call can't be extracted statically"},
+				Down:       []string{"DROP INDEX users_idx;"},
+			},
+			1,
+		},
+		{
+			"not a goose migration",
+			`package migrations
+
+func helper() {}
+`,
+			builder.GooseGoMigration{},
+			0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			have, err := builder.ParseGooseGoMigration("00001_users.go", []byte(tc.src))
+			require.NoError(t, err)
+			require.Len(t, have.Warnings, tc.warnings)
+			for _, warning := range have.Warnings {
+				require.NotZero(t, warning.Position.Line)
+			}
+			have.Warnings = nil
+			require.Equal(t, tc.expected, have)
+		})
+	}
+}
+
+func Test_GooseGoMigrationLines(t *testing.T) {
+	m := builder.GooseGoMigration{
+		Registered: true,
+		Up:         []string{"CREATE TABLE users (id int)"},
+		Down:       []string{"DROP TABLE users;"},
+	}
+	upLines, downLines := builder.BuildMigrationData(m.Lines())
+	require.Equal(t, "BEGIN;CREATE TABLE users (id int);COMMIT;",
+		builder.RemoveSpecialCharacters(builder.JoinMigrationData(upLines)))
+	require.Equal(t, "BEGIN;DROP TABLE users;COMMIT;",
+		builder.RemoveSpecialCharacters(builder.JoinMigrationData(downLines)))
+}
+
+func Test_GooseGoMigrationLines_NotExtracted(t *testing.T) {
+	m := builder.GooseGoMigration{
+		Registered: true,
+		Up: []string{
+			"CREATE TABLE users (id int)",
+			builder.NotExtractedComment + "upUsers: loops can't be extracted statically",
+		},
+	}
+	upLines, _ := builder.BuildMigrationData(m.Lines())
+	require.Equal(t, "BEGIN;CREATE TABLE users (id int);"+
+		"-- migradaptor: not extracted: upUsers: loops can't be extracted staticallyCOMMIT;",
+		builder.RemoveSpecialCharacters(builder.JoinMigrationData(upLines)))
+}
+
+func Test_ParseGoFilename(t *testing.T) {
+	ts, name, err := builder.ParseGoFilename("20230102150405_add_users.go")
+	require.NoError(t, err)
+	require.Equal(t, int64(20230102150405), ts)
+	require.Equal(t, "add_users", name)
+	require.False(t, builder.IsGoMigrationFile("00001_users_test.go"))
+}
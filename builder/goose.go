@@ -8,4 +8,6 @@ var (
 	GooseCmdStatementBegin GooseCmd = "+goose StatementBegin"
 	GooseCmdStatementEnd   GooseCmd = "+goose StatementEnd"
 	GooseCmdNoTransaction  GooseCmd = "NO TRANSACTION"
+	GooseCmdEnvSubOn       GooseCmd = "+goose ENVSUB ON"
+	GooseCmdEnvSubOff      GooseCmd = "+goose ENVSUB OFF"
 )
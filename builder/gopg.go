@@ -0,0 +1,115 @@
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	goPgSqlFilenameReg = regexp.MustCompile(`^(\d+)_(.+?)(\.tx)?\.(up|down)\.sql$`)
+)
+
+type GoPgMigration struct {
+	Version      int64
+	Name         string
+	UpFilename   string
+	DownFilename string
+	UpTx         bool
+	DownTx       bool
+}
+
+func IsGoPgMigrationFile(filename string) bool {
+	return isKeyExists(goPgSqlFilenameReg, filename)
+}
+
+// IsGoPgLayout reports whether the folder uses go-pg/migrations naming,
+// i.e. separate {version}_{name}[.tx].{up|down}.sql files.
+func IsGoPgLayout(filenames []string) bool {
+	for _, filename := range filenames {
+		if IsGoPgMigrationFile(filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectGoPgMigrations pairs up and down files by version and returns them
+// sorted by version, together with the versions registered only in Go files.
+func CollectGoPgMigrations(filenames []string) ([]GoPgMigration, []int64, error) {
+	byVersion := make(map[int64]*GoPgMigration)
+	goVersions := make(map[int64]struct{})
+	for _, filename := range filenames {
+		if fileparts := goFilenameReg.FindStringSubmatch(filename); fileparts != nil {
+			version, err := strconv.ParseInt(fileparts[1], 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parse version of %s: %w", filename, err)
+			}
+			goVersions[version] = struct{}{}
+			continue
+		}
+		fileparts := goPgSqlFilenameReg.FindStringSubmatch(filename)
+		if fileparts == nil {
+			continue
+		}
+		// 1 - version, 2 - name, 3 - tx marker, 4 - direction
+		version, err := strconv.ParseInt(fileparts[1], 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse version of %s: %w", filename, err)
+		}
+		name, tx, direction := fileparts[2], fileparts[3] != "", fileparts[4]
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &GoPgMigration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, nil, fmt.Errorf("%w: version %d is used by %s and %s", ErrGoPgNameMismatch, version, m.Name, name)
+		}
+		switch direction {
+		case "up":
+			if m.UpFilename != "" {
+				return nil, nil, fmt.Errorf("%w: %s and %s", ErrGoPgDuplicateFile, m.UpFilename, filename)
+			}
+			m.UpFilename, m.UpTx = filename, tx
+		case "down":
+			if m.DownFilename != "" {
+				return nil, nil, fmt.Errorf("%w: %s and %s", ErrGoPgDuplicateFile, m.DownFilename, filename)
+			}
+			m.DownFilename, m.DownTx = filename, tx
+		}
+	}
+
+	migrations := make([]GoPgMigration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	goOnly := make([]int64, 0)
+	for version := range goVersions {
+		if _, ok := byVersion[version]; !ok {
+			goOnly = append(goOnly, version)
+		}
+	}
+	sort.Slice(goOnly, func(i, j int) bool {
+		return goOnly[i] < goOnly[j]
+	})
+
+	return migrations, goOnly, nil
+}
+
+func ParseGoPgMigrationData(m GoPgMigration, upLines, downLines []string) MigrationData {
+	return MigrationData{
+		Up:   Section{Lines: upLines, Transaction: m.UpTx, Present: m.UpFilename != ""},
+		Down: Section{Lines: downLines, Transaction: m.DownTx, Present: m.DownFilename != ""},
+	}
+}
+
+func BuildGoPgMigrationData(m GoPgMigration, upLines, downLines []string) ([]string, []string) {
+	return ParseGoPgMigrationData(m, upLines, downLines).Build()
+}
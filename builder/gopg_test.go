@@ -0,0 +1,78 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func Test_CollectGoPgMigrations(t *testing.T) {
+	testCases := []struct {
+		name           string
+		input          []string
+		expected       []builder.GoPgMigration
+		expectedGoOnly []int64
+		expectedErr    error
+	}{
+		{
+			"up and down pair",
+			[]string{"1_initial.tx.up.sql", "1_initial.down.sql"},
+			[]builder.GoPgMigration{
+				{Version: 1, Name: "initial", UpFilename: "1_initial.tx.up.sql", DownFilename: "1_initial.down.sql", UpTx: true},
+			},
+			[]int64{},
+			nil,
+		},
+		{
+			"sorted by version with go migrations",
+			[]string{"10_users.up.sql", "2_companies.tx.up.sql", "2_companies.tx.down.sql", "3_seed.go", "10_users.go", "main.go"},
+			[]builder.GoPgMigration{
+				{Version: 2, Name: "companies", UpFilename: "2_companies.tx.up.sql", DownFilename: "2_companies.tx.down.sql", UpTx: true, DownTx: true},
+				{Version: 10, Name: "users", UpFilename: "10_users.up.sql"},
+			},
+			[]int64{3},
+			nil,
+		},
+		{
+			"name mismatch",
+			[]string{"1_initial.up.sql", "1_other.down.sql"},
+			nil,
+			nil,
+			builder.ErrGoPgNameMismatch,
+		},
+		{
+			"duplicate up file",
+			[]string{"1_initial.up.sql", "1_initial.tx.up.sql"},
+			nil,
+			nil,
+			builder.ErrGoPgDuplicateFile,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			migrations, goOnly, err := builder.CollectGoPgMigrations(tc.input)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expected, migrations)
+			require.Equal(t, tc.expectedGoOnly, goOnly)
+		})
+	}
+}
+
+func Test_BuildGoPgMigrationData(t *testing.T) {
+	m := builder.GoPgMigration{Version: 1, Name: "initial", UpTx: true}
+	up, down := builder.BuildGoPgMigrationData(m,
+		[]string{"CREATE INDEX CONCURRENTLY companies_id_idx ON companies (id);"},
+		[]string{"DROP INDEX companies_id_idx;"},
+	)
+	require.Equal(t, []string{"BEGIN;\n", "CREATE INDEX CONCURRENTLY companies_id_idx ON companies (id);", "COMMIT;\n"}, up)
+	require.Equal(t, []string{"DROP INDEX companies_id_idx;"}, down)
+}
+
+func Test_NextVersion(t *testing.T) {
+	require.Equal(t, int64(5), builder.NextVersion(5, 4))
+	require.Equal(t, int64(5), builder.NextVersion(4, 4))
+	require.Equal(t, int64(5), builder.NextVersion(1, 4))
+}
@@ -0,0 +1,228 @@
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+var (
+	SeverityOff     Severity = "off"
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityOff:     0,
+	SeverityInfo:    1,
+	SeverityWarning: 2,
+	SeverityError:   3,
+}
+
+type LintRule string
+
+var (
+	LintRuleMissingDown         LintRule = "missing-down"
+	LintRuleDropWithoutIfExists LintRule = "drop-without-if-exists"
+	LintRuleConcurrentIndexInTx LintRule = "concurrent-index-in-transaction"
+	LintRuleDuplicateVersion    LintRule = "duplicate-version"
+	LintRuleDownNotReverting    LintRule = "down-not-reverting"
+	LintRuleNonIdempotentEnum   LintRule = "non-idempotent-enum"
+)
+
+func DefaultLintSeverities() map[LintRule]Severity {
+	return map[LintRule]Severity{
+		LintRuleMissingDown:         SeverityWarning,
+		LintRuleDropWithoutIfExists: SeverityWarning,
+		LintRuleConcurrentIndexInTx: SeverityError,
+		LintRuleDuplicateVersion:    SeverityError,
+		LintRuleDownNotReverting:    SeverityWarning,
+		LintRuleNonIdempotentEnum:   SeverityWarning,
+	}
+}
+
+type LintIssue struct {
+	Rule     LintRule
+	Severity Severity
+	Filename string
+	// Line is the source line of the statement, 0 if unknown.
+	Line    int
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return i.Diagnostic().String()
+}
+
+func (i LintIssue) Diagnostic() Diagnostic {
+	return Diagnostic{
+		Severity: i.Severity,
+		Code:     DiagnosticCode(i.Rule),
+		File:     i.Filename,
+		Line:     i.Line,
+		Message:  i.Message,
+	}
+}
+
+var (
+	dropStatementReg     = regexp.MustCompile(`(?is)^DROP\s`)
+	dropIfExistsReg      = regexp.MustCompile(`(?is)^DROP\s+(?:MATERIALIZED\s+VIEW|\w+)\s+(?:CONCURRENTLY\s+)?IF\s+EXISTS\s`)
+	alterDropReg         = regexp.MustCompile(`(?is)\bDROP\s+(?:COLUMN|CONSTRAINT)\s`)
+	alterDropIfExistsReg = regexp.MustCompile(`(?is)\bDROP\s+(?:COLUMN|CONSTRAINT)\s+IF\s+EXISTS\s`)
+	alterTableReg        = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s`)
+	concurrentIndexReg   = regexp.MustCompile(`(?is)\bINDEX\s+CONCURRENTLY\b|^REINDEX\b.*\bCONCURRENTLY\b`)
+	enumAddValueReg      = regexp.MustCompile(`(?is)^ALTER\s+TYPE\s+\S+\s+ADD\s+VALUE\s`)
+	enumAddValueIfNotReg = regexp.MustCompile(`(?is)^ALTER\s+TYPE\s+\S+\s+ADD\s+VALUE\s+IF\s+NOT\s+EXISTS\s`)
+	createdObjectReg     = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?(?:(?:GLOBAL|LOCAL|TEMP|TEMPORARY|UNLOGGED|UNIQUE|MATERIALIZED|CONSTRAINT)\s+)*(?:TABLE|INDEX|TYPE|VIEW|SEQUENCE|FUNCTION|PROCEDURE|SCHEMA|EXTENSION|TRIGGER)\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?` + identPattern)
+	addedColumnReg       = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` + identPattern + `\s+ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?` + identPattern)
+)
+
+func GetSeverity(severity string) (Severity, error) {
+	severity = strings.TrimSpace(severity)
+	severity = strings.ToLower(severity)
+	if _, ok := severityRanks[Severity(severity)]; !ok {
+		return *(new(Severity)), ErrUnknownSeverity
+	}
+	return Severity(severity), nil
+}
+
+// ParseLintRuleSeverity parses rule=severity.
+func ParseLintRuleSeverity(s string) (LintRule, Severity, error) {
+	rule, severity, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s, rule=severity is expected", ErrUnknownLintRule, s)
+	}
+	rule = strings.TrimSpace(rule)
+	if _, ok := DefaultLintSeverities()[LintRule(rule)]; !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrUnknownLintRule, rule)
+	}
+	sev, err := GetSeverity(severity)
+	if err != nil {
+		return "", "", err
+	}
+	return LintRule(rule), sev, nil
+}
+
+// AtLeast reports whether the severity is not lower than the given one.
+func (s Severity) AtLeast(severity Severity) bool {
+	return s != SeverityOff && severityRanks[s] >= severityRanks[severity]
+}
+
+// Lint runs the rules over the parsed migrations in the order they are applied.
+// Rules missing in severities are not run.
+func Lint(migrations []Migration, severities map[LintRule]Severity) []LintIssue {
+	issues := make([]LintIssue, 0)
+	report := func(rule LintRule, filename string, line int, format string, args ...any) {
+		severity, ok := severities[rule]
+		if !ok || severity == SeverityOff {
+			return
+		}
+		issues = append(issues, LintIssue{
+			Rule:     rule,
+			Severity: severity,
+			Filename: filename,
+			Line:     line,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	versions := make(map[int64]string)
+	for _, m := range migrations {
+		if filename, ok := versions[m.Version]; ok {
+			report(LintRuleDuplicateVersion, m.Filename, 0, "version %d is already used by %s", m.Version, filename)
+		} else {
+			versions[m.Version] = m.Filename
+		}
+
+		up, down := SplitStatements(m.Data.Up.Lines), SplitStatements(m.Data.Down.Lines)
+		switch {
+		case !m.Data.Down.Present:
+			report(LintRuleMissingDown, m.Filename, 0, "down section is missing")
+		case len(down) == 0:
+			report(LintRuleMissingDown, m.Filename, 0, "down section is empty")
+		}
+
+		for _, section := range []struct {
+			name        string
+			statements  []string
+			lines       []int
+			transaction bool
+		}{
+			{"up", up, m.Data.Up.StatementLineNumbers(), m.Data.Up.Transaction},
+			{"down", down, m.Data.Down.StatementLineNumbers(), m.Data.Down.Transaction},
+		} {
+			for i, statement := range section.statements {
+				line := 0
+				if section.lines != nil {
+					line = section.lines[i]
+				}
+				if isDropWithoutIfExists(statement) {
+					report(LintRuleDropWithoutIfExists, m.Filename, line, "%s: %s without IF EXISTS", section.name, firstLine(statement))
+				}
+				if section.transaction && concurrentIndexReg.MatchString(statement) {
+					report(LintRuleConcurrentIndexInTx, m.Filename, line, "%s: %s can't run inside a transaction", section.name, firstLine(statement))
+				}
+				if enumAddValueReg.MatchString(statement) && !enumAddValueIfNotReg.MatchString(statement) {
+					report(LintRuleNonIdempotentEnum, m.Filename, line, "%s: %s without IF NOT EXISTS", section.name, firstLine(statement))
+				}
+			}
+		}
+
+		if len(down) != 0 {
+			downText := strings.ToLower(strings.Join(down, "\n"))
+			for _, object := range createdObjects(up) {
+				if !strings.Contains(downText, strings.ToLower(object)) {
+					report(LintRuleDownNotReverting, m.Filename, 0, "down doesn't touch %s created in up", object)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return severityRanks[issues[i].Severity] > severityRanks[issues[j].Severity]
+	})
+	return issues
+}
+
+func isDropWithoutIfExists(statement string) bool {
+	if dropStatementReg.MatchString(statement) {
+		return !dropIfExistsReg.MatchString(statement)
+	}
+	if alterTableReg.MatchString(statement) {
+		return len(alterDropReg.FindAllString(statement, -1)) > len(alterDropIfExistsReg.FindAllString(statement, -1))
+	}
+	return false
+}
+
+// createdObjects returns the unquoted names of the objects created by the statements.
+func createdObjects(statements []string) []string {
+	objects := make([]string, 0)
+	for _, statement := range statements {
+		if parts := createdObjectReg.FindStringSubmatch(statement); parts != nil {
+			objects = append(objects, strings.Trim(lastIdentPart(parts[1]), `"`))
+			continue
+		}
+		if parts := addedColumnReg.FindStringSubmatch(statement); parts != nil {
+			keyword := strings.ToUpper(parts[2])
+			if _, ok := tableConstraintKeywords[keyword]; ok || keyword == "CONSTRAINT" {
+				continue
+			}
+			objects = append(objects, strings.Trim(lastIdentPart(parts[2]), `"`))
+		}
+	}
+	return objects
+}
+
+// HasLintIssues reports whether any of the issues is at least of the given severity.
+func HasLintIssues(issues []LintIssue, failOn Severity) bool {
+	for _, issue := range issues {
+		if issue.Severity.AtLeast(failOn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package builder_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func Test_Lint(t *testing.T) {
+	parse := func(filename string, version int64, src string) builder.Migration {
+		return builder.Migration{
+			Filename: filename,
+			Version:  version,
+			Data:     builder.ParseMigrationData(strings.Split(src, "\n")),
+		}
+	}
+	migrations := []builder.Migration{
+		parse("1-companies.sql", 1, `-- +migrate Up
+CREATE TABLE companies (id int, title text);
+CREATE INDEX CONCURRENTLY companies_title_idx ON companies (title);
+ALTER TYPE mood ADD VALUE 'happy';
+
+-- +migrate Down
+DROP TABLE companies;`),
+		parse("1-users.sql", 1, `-- +migrate Up notransaction
+CREATE INDEX CONCURRENTLY users_title_idx ON users (title);
+ALTER TABLE users DROP COLUMN IF EXISTS age, DROP CONSTRAINT users_age_check;`),
+	}
+
+	issues := builder.Lint(migrations, builder.DefaultLintSeverities())
+	have := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		have = append(have, issue.String())
+	}
+	require.Equal(t, []string{
+		"1-companies.sql: error: concurrent-index-in-transaction: up: CREATE INDEX CONCURRENTLY companies_title_idx ON companies (title) can't run inside a transaction",
+		"1-users.sql: error: duplicate-version: version 1 is already used by 1-companies.sql",
+		"1-companies.sql: warning: non-idempotent-enum: up: ALTER TYPE mood ADD VALUE 'happy' without IF NOT EXISTS",
+		"1-companies.sql: warning: drop-without-if-exists: down: DROP TABLE companies without IF EXISTS",
+		"1-companies.sql: warning: down-not-reverting: down doesn't touch companies_title_idx created in up",
+		"1-users.sql: warning: missing-down: down section is missing",
+		"1-users.sql: warning: drop-without-if-exists: up: ALTER TABLE users DROP COLUMN IF EXISTS age, DROP CONSTRAINT users_age_check without IF EXISTS",
+	}, have)
+	require.True(t, builder.HasLintIssues(issues, builder.SeverityError))
+
+	severities := builder.DefaultLintSeverities()
+	severities[builder.LintRuleConcurrentIndexInTx] = builder.SeverityOff
+	severities[builder.LintRuleDuplicateVersion] = builder.SeverityWarning
+	issues = builder.Lint(migrations, severities)
+	require.Len(t, issues, 6)
+	require.False(t, builder.HasLintIssues(issues, builder.SeverityError))
+	require.True(t, builder.HasLintIssues(issues, builder.SeverityWarning))
+}
+
+func Test_LintLines(t *testing.T) {
+	lines := strings.Split(`-- +migrate Up
+CREATE TABLE companies (id int);
+
+-- +migrate StatementBegin
+CREATE INDEX CONCURRENTLY
+  companies_id_idx ON companies (id);
+-- +migrate StatementEnd
+-- +migrate Down
+DROP TABLE companies;`, "\n")
+	numbers := make([]int, 0, len(lines))
+	for i := range lines {
+		numbers = append(numbers, i+1)
+	}
+	migrations := []builder.Migration{{
+		Filename: "1-companies.sql",
+		Version:  1,
+		Data:     builder.ParseNumberedMigrationData(lines, numbers),
+	}}
+
+	issues := builder.Lint(migrations, builder.DefaultLintSeverities())
+	have := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		have = append(have, issue.String())
+	}
+	require.Equal(t, []string{
+		"1-companies.sql:5: error: concurrent-index-in-transaction: up: CREATE INDEX CONCURRENTLY ... can't run inside a transaction",
+		"1-companies.sql:9: warning: drop-without-if-exists: down: DROP TABLE companies without IF EXISTS",
+		"1-companies.sql: warning: down-not-reverting: down doesn't touch companies_id_idx created in up",
+	}, have)
+}
+
+func Test_ParseLintRuleSeverity(t *testing.T) {
+	rule, severity, err := builder.ParseLintRuleSeverity("missing-down=ERROR")
+	require.NoError(t, err)
+	require.Equal(t, builder.LintRuleMissingDown, rule)
+	require.Equal(t, builder.SeverityError, severity)
+
+	_, _, err = builder.ParseLintRuleSeverity("unknown=error")
+	require.ErrorIs(t, err, builder.ErrUnknownLintRule)
+	_, _, err = builder.ParseLintRuleSeverity("missing-down=fatal")
+	require.ErrorIs(t, err, builder.ErrUnknownSeverity)
+}
@@ -0,0 +1,185 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+)
+
+// ManifestFilename is the file in the destination folder that records
+// which files were produced by the converter.
+const ManifestFilename = ".migradaptor.json"
+
+type Manifest struct {
+	// Files maps the owned file names to the sha256 of the content written.
+	Files map[string]string `json:"files"`
+	// Migrations records how each source migration was converted.
+	Migrations []ManifestMigration `json:"migrations,omitempty"`
+}
+
+type ManifestMigration struct {
+	Source          string   `json:"source,omitempty"`
+	SourceDir       string   `json:"source_dir,omitempty"`
+	OriginalVersion int64    `json:"original_version,omitempty"`
+	Version         int64    `json:"version"`
+	Files           []string `json:"files"`
+	UpTransaction   bool     `json:"up_transaction"`
+	DownTransaction bool     `json:"down_transaction"`
+	Warnings        []string `json:"warnings,omitempty"`
+	// Squashed are the migrations folded into this one, their versions
+	// are the ones a database could be stamped with before the squash.
+	Squashed []ManifestMigration `json:"squashed,omitempty"`
+}
+
+type IncrementalPlan struct {
+	Write  []File
+	Remove []string
+	// Conflicts are the files that differ from what the converter produced.
+	Conflicts []string
+	// Kept are the owned files that are not produced anymore, but were modified.
+	Kept []string
+	// Clashes are the kept files with the version of a produced migration,
+	// golang-migrate refuses a folder with a duplicate version.
+	Clashes []string
+}
+
+func NewManifest() Manifest {
+	return Manifest{Files: make(map[string]string)}
+}
+
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadManifest returns an empty manifest if the dir doesn't have one.
+func ReadManifest(dir string) (Manifest, error) {
+	m := NewManifest()
+	content, err := os.ReadFile(path.Join(dir, ManifestFilename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(content, &m); err != nil {
+		return m, errors.Join(ErrInvalidManifest, err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]string)
+	}
+	return m, nil
+}
+
+func (m Manifest) Marshal() ([]byte, error) {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+func WriteManifest(dir string, m Manifest) error {
+	content, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	return WriteFile(dir, File{Name: ManifestFilename, Content: content})
+}
+
+// PlanIncremental writes only new and changed files and removes the owned
+// files that are not produced anymore. Files not listed in the manifest are
+// never removed, and files that differ from the manifest are conflicts
+// unless force is set.
+func PlanIncremental(dir string, files []File, m Manifest, force bool) (IncrementalPlan, error) {
+	var plan IncrementalPlan
+	changes, err := CompareDir(dir, files)
+	if err != nil {
+		return plan, err
+	}
+	planned := make(map[string]File, len(files))
+	for _, f := range files {
+		planned[f.Name] = f
+	}
+	kept := make([]string, 0)
+	for _, change := range changes {
+		hash, owned := m.Files[change.Name]
+		modified := !owned || hash != ContentHash(change.Old)
+		if change.Status == FileStatusRemoved && modified && (!owned || !force) {
+			kept = append(kept, change.Name)
+		}
+		switch change.Status {
+		case FileStatusNew:
+			plan.Write = append(plan.Write, planned[change.Name])
+		case FileStatusChanged:
+			if modified && !force {
+				plan.Conflicts = append(plan.Conflicts, change.Name)
+				continue
+			}
+			plan.Write = append(plan.Write, planned[change.Name])
+		case FileStatusRemoved:
+			switch {
+			case !owned:
+			case modified && !force:
+				plan.Kept = append(plan.Kept, change.Name)
+			default:
+				plan.Remove = append(plan.Remove, change.Name)
+			}
+		}
+	}
+	plan.Clashes = VersionClashes(kept, files)
+	return plan, nil
+}
+
+// VersionClashes returns the golang-migrate files among kept that have the
+// version of one of the files.
+func VersionClashes(kept []string, files []File) []string {
+	versions := make(map[int64]struct{}, len(files))
+	for _, f := range files {
+		if version, ok := migrationFileVersion(f.Name); ok {
+			versions[version] = struct{}{}
+		}
+	}
+	clashes := make([]string, 0)
+	for _, name := range kept {
+		if version, ok := migrationFileVersion(name); ok {
+			if _, clash := versions[version]; clash {
+				clashes = append(clashes, name)
+			}
+		}
+	}
+	return clashes
+}
+
+func migrationFileVersion(filename string) (int64, bool) {
+	parts := migrationFilenameReg.FindStringSubmatch(filename)
+	if parts == nil {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(parts[1], 10, 64)
+	return version, err == nil
+}
+
+// ApplyIncremental executes the plan and records the produced files in the manifest.
+func ApplyIncremental(dir string, files []File, plan IncrementalPlan, m Manifest) error {
+	for _, f := range plan.Write {
+		if err := WriteFile(dir, f); err != nil {
+			return err
+		}
+	}
+	for _, name := range plan.Remove {
+		if err := os.Remove(path.Join(dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		delete(m.Files, name)
+	}
+	for _, f := range files {
+		m.Files[f.Name] = ContentHash(f.Content)
+	}
+	return WriteManifest(dir, m)
+}
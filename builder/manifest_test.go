@@ -0,0 +1,81 @@
+package builder_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func Test_PlanIncremental(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	files := []builder.File{
+		{Name: "1_init.up.sql", Content: []byte("CREATE TABLE t (id int);\n")},
+		{Name: "1_init.down.sql", Content: []byte("DROP TABLE t;\n")},
+	}
+	write("0_hand.up.sql", "CREATE SCHEMA hand;\n")
+
+	manifest, err := builder.ReadManifest(dir)
+	require.NoError(t, err)
+	plan, err := builder.PlanIncremental(dir, files, manifest, false)
+	require.NoError(t, err)
+	require.Equal(t, files, plan.Write)
+	require.Empty(t, plan.Remove)
+	require.Empty(t, plan.Conflicts)
+	require.NoError(t, builder.ApplyIncremental(dir, files, plan, manifest))
+
+	// the file was edited by hand and the old migration isn't produced anymore
+	write("1_init.up.sql", "CREATE TABLE t (id bigint);\n")
+	files = []builder.File{
+		{Name: "1_init.up.sql", Content: []byte("CREATE TABLE t (id int, title text);\n")},
+		{Name: "2_users.up.sql", Content: []byte("CREATE TABLE users (id int);\n")},
+	}
+	manifest, err = builder.ReadManifest(dir)
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 2)
+	plan, err = builder.PlanIncremental(dir, files, manifest, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1_init.up.sql"}, plan.Conflicts)
+	require.Equal(t, []string{"1_init.down.sql"}, plan.Remove)
+	require.Equal(t, files[1:], plan.Write)
+
+	plan, err = builder.PlanIncremental(dir, files, manifest, true)
+	require.NoError(t, err)
+	require.Empty(t, plan.Conflicts)
+	require.NoError(t, builder.ApplyIncremental(dir, files, plan, manifest))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	require.Equal(t, []string{builder.ManifestFilename, "0_hand.up.sql", "1_init.up.sql", "2_users.up.sql"}, names)
+	manifest, err = builder.ReadManifest(dir)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"1_init.up.sql":  builder.ContentHash(files[0].Content),
+		"2_users.up.sql": builder.ContentHash(files[1].Content),
+	}, manifest.Files)
+}
+
+func Test_PlanIncremental_VersionClashes(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"3_add_x.up.sql", "3_add_x.down.sql", "4_add_y.up.sql", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;\n"), 0o644))
+	}
+	files := []builder.File{
+		{Name: "03_c.up.sql", Content: []byte("CREATE TABLE c (id int);\n")},
+		{Name: "03_c.down.sql", Content: []byte("DROP TABLE c;\n")},
+	}
+
+	plan, err := builder.PlanIncremental(dir, files, builder.NewManifest(), true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"3_add_x.down.sql", "3_add_x.up.sql"}, plan.Clashes)
+}
@@ -0,0 +1,67 @@
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	migrationFilenameReg = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+	nameSeparatorsReg    = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// MigrationName returns the name for the filenames, runs of characters
+// other than letters, digits and underscores become an underscore.
+func MigrationName(name string) (string, error) {
+	name = strings.Trim(nameSeparatorsReg.ReplaceAllString(strings.TrimSpace(name), "_"), "_")
+	if name == "" {
+		return "", ErrNoMigrationName
+	}
+	return name, nil
+}
+
+// NewMigrationVersion returns the version of a migration added after the
+// golang-migrate files, formatted like theirs. Versionings other than
+// timestamp and sequential follow the files: it's a timestamp if they use
+// timestamps or there are none, the last version + 1 otherwise.
+func NewMigrationVersion(filenames []string, v Versioning, now time.Time) (string, error) {
+	var (
+		last       int64
+		lastDigits string
+	)
+	for _, filename := range filenames {
+		parts := migrationFilenameReg.FindStringSubmatch(filename)
+		if parts == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidVersion, filename)
+		}
+		if version > last {
+			last, lastDigits = version, parts[1]
+		}
+	}
+
+	if v != VersioningTimestamp && v != VersioningSequential {
+		v = VersioningSequential
+		if last == 0 || IsTimestampVersion(last) {
+			v = VersioningTimestamp
+		}
+	}
+	if v == VersioningTimestamp {
+		timestamp, _ := strconv.ParseInt(now.UTC().Format(timestampVersionLayout), 10, 64)
+		return strconv.FormatInt(NextVersion(timestamp, last), 10), nil
+	}
+	width := sequentialVersionWidth
+	if last != 0 {
+		width = 0
+		if strings.HasPrefix(lastDigits, "0") {
+			width = len(lastDigits)
+		}
+	}
+	return fmt.Sprintf("%0*d", width, last+1), nil
+}
@@ -0,0 +1,50 @@
+package builder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func TestNewMigrationVersion(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2023, 6, 1, 12, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		name       string
+		filenames  []string
+		versioning builder.Versioning
+		expected   string
+		err        error
+	}{
+		{"empty folder", nil, "", "20230601123000", nil},
+		{"empty folder sequential", []string{"README.md"}, builder.VersioningSequential, "000001", nil},
+		{"timestamps", []string{"20230101000000_init.up.sql", "20230101000000_init.down.sql"}, "", "20230601123000", nil},
+		{"timestamp in the future", []string{"20991231000000_init.up.sql"}, "", "20991231000001", nil},
+		{"padded sequence", []string{"000001_init.up.sql", "000002_users.up.sql", "000002_users.down.sql"}, "", "000003", nil},
+		{"plain sequence", []string{"9_init.up.sql"}, "", "10", nil},
+		{"timestamp after sequence", []string{"9_init.up.sql"}, builder.VersioningTimestamp, "20230601123000", nil},
+		{"versioning of the folder", []string{"9_init.up.sql"}, builder.VersioningBump, "10", nil},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			have, err := builder.NewMigrationVersion(tc.filenames, tc.versioning, now)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, have)
+		})
+	}
+}
+
+func TestMigrationName(t *testing.T) {
+	t.Parallel()
+	name, err := builder.MigrationName(" add users-table ")
+	require.NoError(t, err)
+	require.Equal(t, "add_users_table", name)
+
+	_, err = builder.MigrationName(" - ")
+	require.ErrorIs(t, err, builder.ErrNoMigrationName)
+}
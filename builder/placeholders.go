@@ -0,0 +1,151 @@
+package builder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type PlaceholderMode string
+
+var (
+	PlaceholderModeKeep       PlaceholderMode = "keep"
+	PlaceholderModeSubstitute PlaceholderMode = "substitute"
+	PlaceholderModeRewrite    PlaceholderMode = "rewrite"
+)
+
+var (
+	// ${VAR} and ${VAR:-default}
+	placeholderReg = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+)
+
+// DefaultPlaceholderFormat rewrites placeholders to text/template actions.
+const DefaultPlaceholderFormat = "{{.%s}}"
+
+type Placeholders struct {
+	Mode PlaceholderMode
+	// Lookup resolves variables for PlaceholderModeSubstitute.
+	Lookup func(name string) (string, bool)
+	// Format is a fmt format with a single %s verb for PlaceholderModeRewrite.
+	Format string
+}
+
+func GetPlaceholderMode(mode string) (PlaceholderMode, error) {
+	mode = strings.TrimSpace(mode)
+	mode = strings.ToLower(mode)
+	switch mode {
+	case "", string(PlaceholderModeKeep):
+		return PlaceholderModeKeep, nil
+	case string(PlaceholderModeSubstitute):
+		return PlaceholderModeSubstitute, nil
+	case string(PlaceholderModeRewrite):
+		return PlaceholderModeRewrite, nil
+	default:
+		return *(new(PlaceholderMode)), ErrUnknownPlaceholderMode
+	}
+}
+
+// ProcessPlaceholders drops goose ENVSUB directives and handles ${VAR}
+// placeholders according to the mode. If the file has ENVSUB directives,
+// only the lines between ENVSUB ON and ENVSUB OFF are processed, otherwise
+// the whole file is. Names of the placeholders left unresolved are returned.
+func ProcessPlaceholders(lines []string, p Placeholders) ([]string, []string) {
+	result, _, names := ProcessPlaceholderLines(lines, p)
+	return result, names
+}
+
+// ProcessPlaceholderLines is ProcessPlaceholders that also returns the
+// number of the source line of each processed one, counting from 1.
+func ProcessPlaceholderLines(lines []string, p Placeholders) ([]string, []int, []string) {
+	hasDirectives := false
+	for _, line := range lines {
+		if IsContainsCmd(line, GooseCmdEnvSubOn, GooseCmdEnvSubOff) {
+			hasDirectives = true
+			break
+		}
+	}
+
+	result := make([]string, 0, len(lines))
+	numbers := make([]int, 0, len(lines))
+	unresolved := make(map[string]struct{})
+	enabled := !hasDirectives
+	for i, line := range lines {
+		switch {
+		case IsContainsCmd(line, GooseCmdEnvSubOn):
+			enabled = true
+			continue
+		case IsContainsCmd(line, GooseCmdEnvSubOff):
+			enabled = false
+			continue
+		}
+		if enabled {
+			line = placeholderReg.ReplaceAllStringFunc(line, func(placeholder string) string {
+				return replacePlaceholder(placeholder, p, unresolved)
+			})
+		}
+		result = append(result, line)
+		numbers = append(numbers, i+1)
+	}
+
+	names := make([]string, 0, len(unresolved))
+	for name := range unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return result, numbers, names
+}
+
+func replacePlaceholder(placeholder string, p Placeholders, unresolved map[string]struct{}) string {
+	// 1 - name, 2 - default part, 3 - default value
+	parts := placeholderReg.FindStringSubmatch(placeholder)
+	name, hasDefault, defaultValue := parts[1], parts[2] != "", parts[3]
+	switch p.Mode {
+	case PlaceholderModeSubstitute:
+		if p.Lookup != nil {
+			if value, ok := p.Lookup(name); ok {
+				return value
+			}
+		}
+		if hasDefault {
+			return defaultValue
+		}
+	case PlaceholderModeRewrite:
+		format := p.Format
+		if format == "" {
+			format = DefaultPlaceholderFormat
+		}
+		return fmt.Sprintf(format, name)
+	}
+	unresolved[name] = struct{}{}
+	return placeholder
+}
+
+// ReadVarsFile reads KEY=VALUE lines, empty lines and lines starting
+// with # are skipped.
+func ReadVarsFile(r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d", ErrInvalidVarsFile, lineNum)
+		}
+		name = strings.TrimSpace(strings.TrimPrefix(name, "export "))
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
@@ -0,0 +1,97 @@
+package builder_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func Test_ProcessPlaceholders(t *testing.T) {
+	vars := map[string]string{"SCHEMA": "billing"}
+	lookup := func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+	testCases := []struct {
+		name               string
+		input              string
+		placeholders       builder.Placeholders
+		expected           string
+		expectedUnresolved []string
+	}{
+		{
+			"keep without directives",
+			`CREATE SCHEMA ${SCHEMA};`,
+			builder.Placeholders{Mode: builder.PlaceholderModeKeep},
+			`CREATE SCHEMA ${SCHEMA};`,
+			[]string{"SCHEMA"},
+		},
+		{
+			"substitute whole file",
+			`CREATE SCHEMA ${SCHEMA};
+CREATE TABLE ${SCHEMA}.t (id int) TABLESPACE ${TABLESPACE:-pg_default};
+GRANT ALL ON ${SCHEMA}.t TO ${ROLE};`,
+			builder.Placeholders{Mode: builder.PlaceholderModeSubstitute, Lookup: lookup},
+			`CREATE SCHEMA billing;
+CREATE TABLE billing.t (id int) TABLESPACE pg_default;
+GRANT ALL ON billing.t TO ${ROLE};`,
+			[]string{"ROLE"},
+		},
+		{
+			"substitute only envsub regions",
+			`-- +goose Up
+-- +goose ENVSUB ON
+CREATE SCHEMA ${SCHEMA};
+-- +goose ENVSUB OFF
+SELECT '${SCHEMA}';`,
+			builder.Placeholders{Mode: builder.PlaceholderModeSubstitute, Lookup: lookup},
+			`-- +goose Up
+CREATE SCHEMA billing;
+SELECT '${SCHEMA}';`,
+			[]string{},
+		},
+		{
+			"rewrite",
+			`CREATE SCHEMA ${SCHEMA};`,
+			builder.Placeholders{Mode: builder.PlaceholderModeRewrite},
+			`CREATE SCHEMA {{.SCHEMA}};`,
+			[]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			have, unresolved := builder.ProcessPlaceholders(strings.Split(tc.input, "\n"), tc.placeholders)
+			require.Equal(t, tc.expected, strings.Join(have, "\n"))
+			require.Equal(t, tc.expectedUnresolved, unresolved)
+		})
+	}
+}
+
+func Test_ProcessPlaceholderLines(t *testing.T) {
+	lines, numbers, _ := builder.ProcessPlaceholderLines([]string{
+		"CREATE TABLE users (id int);",
+		"-- +goose ENVSUB ON",
+		"CREATE SCHEMA ${SCHEMA};",
+		"-- +goose ENVSUB OFF",
+		"CREATE TABLE emails (id int);",
+	}, builder.Placeholders{Mode: builder.PlaceholderModeKeep})
+	require.Len(t, lines, 3)
+	require.Equal(t, []int{1, 3, 5}, numbers)
+}
+
+func Test_ReadVarsFile(t *testing.T) {
+	vars, err := builder.ReadVarsFile(strings.NewReader(`
+# schema settings
+SCHEMA=billing
+export ROLE="app"
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"SCHEMA": "billing", "ROLE": "app"}, vars)
+
+	_, err = builder.ReadVarsFile(strings.NewReader("SCHEMA"))
+	require.ErrorIs(t, err, builder.ErrInvalidVarsFile)
+}
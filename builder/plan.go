@@ -0,0 +1,122 @@
+package builder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type File struct {
+	Name    string
+	Content []byte
+}
+
+type FileStatus string
+
+var (
+	FileStatusNew       FileStatus = "new"
+	FileStatusChanged   FileStatus = "changed"
+	FileStatusUnchanged FileStatus = "unchanged"
+	FileStatusRemoved   FileStatus = "removed"
+)
+
+type FileChange struct {
+	Name   string
+	Status FileStatus
+	Old    []byte
+	New    []byte
+}
+
+// MigrationFiles builds the up and down files, version is formatted as in the filenames.
+func MigrationFiles(version, name string, upLines, downLines []string) []File {
+	return []File{
+		{Name: MigrationFilename(version, name, "up"), Content: BuildBuffer(upLines)},
+		{Name: MigrationFilename(version, name, "down"), Content: BuildBuffer(downLines)},
+	}
+}
+
+func MigrationFilename(version, name, direction string) string {
+	return fmt.Sprintf("%s_%s.%s.sql", version, name, direction)
+}
+
+// CompareDir compares the planned files with the regular files of the dir.
+// Files of the dir missing in the plan are reported as removed.
+func CompareDir(dir string, files []File) ([]FileChange, error) {
+	existing := make(map[string][]byte)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || entry.Name() == ManifestFilename {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		existing[entry.Name()] = content
+	}
+	return CompareFiles(existing, files), nil
+}
+
+func CompareFiles(existing map[string][]byte, files []File) []FileChange {
+	changes := make([]FileChange, 0, len(files))
+	planned := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		planned[f.Name] = struct{}{}
+		old, ok := existing[f.Name]
+		switch {
+		case !ok:
+			changes = append(changes, FileChange{Name: f.Name, Status: FileStatusNew, New: f.Content})
+		case bytes.Equal(old, f.Content):
+			changes = append(changes, FileChange{Name: f.Name, Status: FileStatusUnchanged, Old: old, New: f.Content})
+		default:
+			changes = append(changes, FileChange{Name: f.Name, Status: FileStatusChanged, Old: old, New: f.Content})
+		}
+	}
+	removed := make([]string, 0)
+	for name := range existing {
+		if _, ok := planned[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		changes = append(changes, FileChange{Name: name, Status: FileStatusRemoved, Old: existing[name]})
+	}
+	return changes
+}
+
+func UnifiedDiff(change FileChange) (string, error) {
+	fromFile, toFile := "a/"+change.Name, "b/"+change.Name
+	switch change.Status {
+	case FileStatusNew:
+		fromFile = "/dev/null"
+	case FileStatusRemoved:
+		toFile = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(change.Old),
+		B:        splitLines(change.New),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+}
+
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
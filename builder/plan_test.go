@@ -0,0 +1,52 @@
+package builder_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func Test_CompareDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1_init.up.sql"), []byte("CREATE TABLE t (id int);\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1_init.down.sql"), []byte("DROP TABLE t;\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("migrations\n"), 0o644))
+
+	files := []builder.File{
+		{Name: "1_init.up.sql", Content: []byte("CREATE TABLE t (id bigint);\n")},
+		{Name: "1_init.down.sql", Content: []byte("DROP TABLE t;\n")},
+		{Name: "2_users.up.sql", Content: []byte("CREATE TABLE users (id int);\n")},
+	}
+	changes, err := builder.CompareDir(dir, files)
+	require.NoError(t, err)
+
+	statuses := make(map[string]builder.FileStatus, len(changes))
+	for _, change := range changes {
+		statuses[change.Name] = change.Status
+	}
+	require.Equal(t, map[string]builder.FileStatus{
+		"1_init.up.sql":   builder.FileStatusChanged,
+		"1_init.down.sql": builder.FileStatusUnchanged,
+		"2_users.up.sql":  builder.FileStatusNew,
+		"README.md":       builder.FileStatusRemoved,
+	}, statuses)
+
+	diff, err := builder.UnifiedDiff(changes[0])
+	require.NoError(t, err)
+	require.Equal(t, `--- a/1_init.up.sql
++++ b/1_init.up.sql
+@@ -1 +1 @@
+-CREATE TABLE t (id int);
++CREATE TABLE t (id bigint);
+`, diff)
+
+	changes, err = builder.CompareDir(filepath.Join(dir, "missing"), files)
+	require.NoError(t, err)
+	for _, change := range changes {
+		require.Equal(t, builder.FileStatusNew, change.Status)
+	}
+}
@@ -0,0 +1,225 @@
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const GeneratedDownComment = "-- generated by migradaptor from the up section, review before applying"
+
+const identPattern = `((?:"[^"]+"|[\w$]+)(?:\.(?:"[^"]+"|[\w$]+))*)`
+
+type inverseRule struct {
+	reg     *regexp.Regexp
+	inverse func(parts []string) string
+}
+
+var (
+	inverseRules = []inverseRule{
+		{
+			regexp.MustCompile(`(?is)^CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + identPattern),
+			func(parts []string) string { return fmt.Sprintf("DROP TABLE IF EXISTS %s;", parts[1]) },
+		},
+		{
+			regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?` + identPattern + `\s+ON\s`),
+			func(parts []string) string {
+				if parts[1] != "" {
+					return fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s;", parts[2])
+				}
+				return fmt.Sprintf("DROP INDEX IF EXISTS %s;", parts[2])
+			},
+		},
+		{
+			regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` + identPattern + `\s+ADD\s+CONSTRAINT\s+` + identPattern + `\s`),
+			func(parts []string) string {
+				return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", parts[1], parts[2])
+			},
+		},
+		{
+			regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` + identPattern + `\s+ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?` + identPattern + `\s+.+$`),
+			func(parts []string) string {
+				if _, ok := tableConstraintKeywords[strings.ToUpper(parts[2])]; ok {
+					return ""
+				}
+				// several actions of one statement
+				if hasTopLevelComma(parts[0]) {
+					return ""
+				}
+				return fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s;", parts[1], parts[2])
+			},
+		},
+		{
+			regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` + identPattern + `\s+RENAME\s+(?:COLUMN\s+)?` + identPattern + `\s+TO\s+` + identPattern + `$`),
+			func(parts []string) string {
+				if strings.EqualFold(parts[2], "TO") {
+					return ""
+				}
+				return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", parts[1], parts[3], parts[2])
+			},
+		},
+		{
+			regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` + identPattern + `\s+RENAME\s+TO\s+` + identPattern + `$`),
+			func(parts []string) string {
+				return fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", renamedIdent(parts[1], parts[2]), lastIdentPart(parts[1]))
+			},
+		},
+		{
+			regexp.MustCompile(`(?is)^CREATE\s+TYPE\s+` + identPattern + `\s+AS\s`),
+			func(parts []string) string { return fmt.Sprintf("DROP TYPE IF EXISTS %s;", parts[1]) },
+		},
+		{
+			regexp.MustCompile(`(?is)^CREATE\s+EXTENSION\s+(?:IF\s+NOT\s+EXISTS\s+)?` + identPattern),
+			func(parts []string) string { return fmt.Sprintf("DROP EXTENSION IF EXISTS %s;", parts[1]) },
+		},
+		{
+			regexp.MustCompile(`(?is)^CREATE\s+SCHEMA\s+(?:IF\s+NOT\s+EXISTS\s+)?` + identPattern),
+			func(parts []string) string { return fmt.Sprintf("DROP SCHEMA IF EXISTS %s;", parts[1]) },
+		},
+		{
+			regexp.MustCompile(`(?is)^CREATE\s+(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?SEQUENCE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + identPattern),
+			func(parts []string) string { return fmt.Sprintf("DROP SEQUENCE IF EXISTS %s;", parts[1]) },
+		},
+		{
+			regexp.MustCompile(`(?is)^CREATE\s+(MATERIALIZED\s+)?VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?` + identPattern),
+			func(parts []string) string {
+				if parts[1] != "" {
+					return fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %s;", parts[2])
+				}
+				return fmt.Sprintf("DROP VIEW IF EXISTS %s;", parts[2])
+			},
+		},
+		{
+			regexp.MustCompile(`(?is)^CREATE\s+(FUNCTION|PROCEDURE)\s+` + identPattern + `\s*\(([^)]*)\)`),
+			func(parts []string) string {
+				return fmt.Sprintf("DROP %s IF EXISTS %s(%s);", strings.ToUpper(parts[1]), parts[2], parts[3])
+			},
+		},
+		{
+			regexp.MustCompile(`(?is)^CREATE\s+(?:CONSTRAINT\s+)?TRIGGER\s+` + identPattern + `\s.*?\sON\s+` + identPattern),
+			func(parts []string) string {
+				return fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;", parts[1], parts[2])
+			},
+		},
+	}
+	tableConstraintKeywords = map[string]struct{}{
+		"PRIMARY": {},
+		"UNIQUE":  {},
+		"CHECK":   {},
+		"FOREIGN": {},
+		"EXCLUDE": {},
+	}
+	// statements that don't need an inverse
+	ignoredStatementReg = regexp.MustCompile(`(?is)^COMMENT\s+ON\s`)
+	// session settings are repeated at the beginning of the down section
+	settingStatementReg = regexp.MustCompile(`(?is)^SET\s`)
+)
+
+// GenerateDown derives the inverse of the up statements in reverse order.
+// The statements that can't be inverted are returned as irreversible.
+func GenerateDown(upLines []string) ([]string, []string) {
+	settings := make([]string, 0)
+	inverted := make([]string, 0)
+	irreversible := make([]string, 0)
+	for _, statement := range SplitStatements(upLines) {
+		if ignoredStatementReg.MatchString(statement) {
+			continue
+		}
+		if settingStatementReg.MatchString(statement) {
+			settings = append(settings, statement+";")
+			continue
+		}
+		inverse := invertStatement(statement)
+		if inverse == "" {
+			irreversible = append(irreversible, statement)
+			continue
+		}
+		inverted = append(inverted, inverse)
+	}
+
+	down := make([]string, 0, len(settings)+len(inverted)+len(irreversible)+1)
+	down = append(down, GeneratedDownComment)
+	down = append(down, settings...)
+	for i := len(inverted) - 1; i >= 0; i-- {
+		down = append(down, inverted[i])
+	}
+	for _, statement := range irreversible {
+		down = append(down, "-- irreversible: "+firstLine(statement))
+	}
+	return down, irreversible
+}
+
+// GenerateDown fills an empty down section with the inverse of the up one.
+// It returns false if the down section already has statements.
+func (d *MigrationData) GenerateDown() ([]string, bool) {
+	if len(SplitStatements(d.Down.Lines)) != 0 {
+		return nil, false
+	}
+	down, irreversible := GenerateDown(d.Up.Lines)
+	d.Down = Section{
+		Lines:       down,
+		Transaction: d.Up.Transaction,
+		Present:     true,
+	}
+	return irreversible, true
+}
+
+func invertStatement(statement string) string {
+	for _, rule := range inverseRules {
+		parts := rule.reg.FindStringSubmatch(statement)
+		if parts == nil {
+			continue
+		}
+		if inverse := rule.inverse(parts); inverse != "" {
+			return inverse
+		}
+	}
+	return ""
+}
+
+// renamedIdent keeps the schema of the original table for the renamed one.
+func renamedIdent(original, renamed string) string {
+	if i := strings.LastIndexByte(original, '.'); i != -1 && !strings.Contains(renamed, ".") {
+		return original[:i+1] + renamed
+	}
+	return renamed
+}
+
+func lastIdentPart(ident string) string {
+	if i := strings.LastIndexByte(ident, '.'); i != -1 {
+		return ident[i+1:]
+	}
+	return ident
+}
+
+// hasTopLevelComma reports whether the statement has a comma outside
+// parentheses and quotes, like the one separating ALTER TABLE actions.
+func hasTopLevelComma(statement string) bool {
+	depth := 0
+	for i := 0; i < len(statement); i++ {
+		switch c := statement[i]; c {
+		case '\'', '"':
+			end := strings.IndexByte(statement[i+1:], c)
+			if end == -1 {
+				return false
+			}
+			i += end + 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		return strings.TrimSpace(s[:i]) + " ..."
+	}
+	return s
+}
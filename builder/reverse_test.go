@@ -0,0 +1,114 @@
+package builder_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func Test_GenerateDown(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		up                   string
+		expectedDown         []string
+		expectedIrreversible []string
+	}{
+		{
+			"tables and indexes in reverse order",
+			`SET search_path TO billing;
+CREATE TABLE IF NOT EXISTS companies (id int, title text);
+CREATE UNIQUE INDEX CONCURRENTLY companies_title_idx ON companies (title);
+COMMENT ON TABLE companies IS 'companies';`,
+			[]string{
+				builder.GeneratedDownComment,
+				"SET search_path TO billing;",
+				"DROP INDEX CONCURRENTLY IF EXISTS companies_title_idx;",
+				"DROP TABLE IF EXISTS companies;",
+			},
+			[]string{},
+		},
+		{
+			"alter table",
+			`ALTER TABLE companies ADD COLUMN IF NOT EXISTS owner_id bigint NOT NULL DEFAULT 0;
+ALTER TABLE companies ADD CONSTRAINT companies_owner_fk FOREIGN KEY (owner_id) REFERENCES users (id);
+ALTER TABLE companies ADD PRIMARY KEY (id);
+ALTER TABLE public.companies RENAME TO organizations;
+ALTER TABLE organizations RENAME COLUMN title TO name;`,
+			[]string{
+				builder.GeneratedDownComment,
+				"ALTER TABLE organizations RENAME COLUMN name TO title;",
+				"ALTER TABLE public.organizations RENAME TO companies;",
+				"ALTER TABLE companies DROP CONSTRAINT IF EXISTS companies_owner_fk;",
+				"ALTER TABLE companies DROP COLUMN IF EXISTS owner_id;",
+				"-- irreversible: ALTER TABLE companies ADD PRIMARY KEY (id)",
+			},
+			[]string{"ALTER TABLE companies ADD PRIMARY KEY (id)"},
+		},
+		{
+			"add column with commas",
+			`ALTER TABLE products ADD COLUMN price numeric(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN tags text DEFAULT 'a,b';
+ALTER TABLE products ADD COLUMN sku text, ADD COLUMN weight int;`,
+			[]string{
+				builder.GeneratedDownComment,
+				"ALTER TABLE products DROP COLUMN IF EXISTS tags;",
+				"ALTER TABLE products DROP COLUMN IF EXISTS price;",
+				"-- irreversible: ALTER TABLE products ADD COLUMN sku text, ADD COLUMN weight int",
+			},
+			[]string{"ALTER TABLE products ADD COLUMN sku text, ADD COLUMN weight int"},
+		},
+		{
+			"types, extensions and functions",
+			`CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+CREATE TYPE mood AS ENUM ('sad', 'happy');
+CREATE FUNCTION add(a integer, b integer) RETURNS integer AS $$ SELECT a + b; $$ LANGUAGE SQL;
+CREATE OR REPLACE VIEW happy_users AS SELECT 1;
+UPDATE users SET mood = 'happy';`,
+			[]string{
+				builder.GeneratedDownComment,
+				"DROP FUNCTION IF EXISTS add(a integer, b integer);",
+				"DROP TYPE IF EXISTS mood;",
+				`DROP EXTENSION IF EXISTS "uuid-ossp";`,
+				"-- irreversible: CREATE OR REPLACE VIEW happy_users AS SELECT 1",
+				"-- irreversible: UPDATE users SET mood = 'happy'",
+			},
+			[]string{
+				"CREATE OR REPLACE VIEW happy_users AS SELECT 1",
+				"UPDATE users SET mood = 'happy'",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			down, irreversible := builder.GenerateDown(strings.Split(tc.up, "\n"))
+			require.Equal(t, tc.expectedDown, down)
+			require.Equal(t, tc.expectedIrreversible, irreversible)
+		})
+	}
+}
+
+func Test_MigrationDataGenerateDown(t *testing.T) {
+	data := builder.ParseMigrationData(strings.Split(`-- migrate:up
+CREATE TABLE companies (id int);
+
+-- migrate:down
+`, "\n"))
+	irreversible, ok := data.GenerateDown()
+	require.True(t, ok)
+	require.Empty(t, irreversible)
+	_, down := data.Build()
+	require.Equal(t, "BEGIN;-- generated by migradaptor from the up section, review before applyingDROP TABLE IF EXISTS companies;COMMIT;",
+		builder.RemoveSpecialCharacters(builder.JoinMigrationData(down)))
+
+	data = builder.ParseMigrationData(strings.Split(`-- migrate:up
+CREATE TABLE companies (id int);
+-- migrate:down
+DROP TABLE companies;
+`, "\n"))
+	_, ok = data.GenerateDown()
+	require.False(t, ok)
+}
@@ -19,7 +19,7 @@ var (
 )
 
 var (
-	filenameReg = regexp.MustCompile(`(\d{0,15})(-|_)(.*)(.sql)`)
+	filenameReg = regexp.MustCompile(`(\d+)(-|_)(.*)(.sql)`)
 )
 
 func ParseFilename(filename string) (int64, string, error) {
@@ -35,8 +35,6 @@ func ParseFilename(filename string) (int64, string, error) {
 	}
 	ts := fel[1]
 	name := fel[3]
-	println(ts)
-	println(name)
 	tsInt, err := strconv.ParseInt(ts, 10, 64)
 	if err != nil {
 		return 0, "", errors.Wrap(err, "parse timestamp")
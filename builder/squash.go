@@ -0,0 +1,55 @@
+package builder
+
+// SquashedMigration is a part of the squashed migrations.
+type SquashedMigration struct {
+	Data MigrationData
+	// Parts are the indexes of the migrations folded into the part.
+	Parts []int
+}
+
+// Squash folds the migrations, given in the order they are applied, into as
+// few parts as the transaction modes allow. Up sections are concatenated in
+// order and down sections in reverse order. Transactional migrations are
+// folded together, a migration with a non-transactional section is kept in
+// its own part, as its statements can't share a multi-statement exec.
+func Squash(migrations []Migration) []SquashedMigration {
+	squashed := make([]SquashedMigration, 0)
+	for i, m := range migrations {
+		last := len(squashed) - 1
+		if last < 0 || !isSquashable(m) || !isSquashable(migrations[squashed[last].Parts[0]]) {
+			squashed = append(squashed, SquashedMigration{})
+			last++
+		}
+		squashed[last].Parts = append(squashed[last].Parts, i)
+	}
+
+	for i := range squashed {
+		part := &squashed[i]
+		part.Data.Up.Lines = make([]string, 0)
+		part.Data.Down.Lines = make([]string, 0)
+		for j, index := range part.Parts {
+			m := migrations[index]
+			part.Data.Up.Lines = append(part.Data.Up.Lines, "-- "+m.Filename)
+			part.Data.Up.Lines = append(part.Data.Up.Lines, m.Data.Up.Lines...)
+			part.Data.Up.Transaction = part.Data.Up.Transaction || m.Data.Up.Transaction
+			part.Data.Up.Present = part.Data.Up.Present || m.Data.Up.Present
+			part.Data.Down.Transaction = part.Data.Down.Transaction || m.Data.Down.Transaction
+			part.Data.Down.Present = part.Data.Down.Present || m.Data.Down.Present
+
+			down := migrations[part.Parts[len(part.Parts)-1-j]]
+			part.Data.Down.Lines = append(part.Data.Down.Lines, "-- "+down.Filename)
+			part.Data.Down.Lines = append(part.Data.Down.Lines, down.Data.Down.Lines...)
+		}
+	}
+	return squashed
+}
+
+// isSquashable reports whether the sections with statements are transactional.
+func isSquashable(m Migration) bool {
+	for _, section := range []Section{m.Data.Up, m.Data.Down} {
+		if !section.Transaction && len(SplitStatements(section.Lines)) != 0 {
+			return false
+		}
+	}
+	return true
+}
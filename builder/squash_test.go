@@ -0,0 +1,52 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func TestSquash(t *testing.T) {
+	t.Parallel()
+	migration := func(filename string, upTx bool, up, down string) builder.Migration {
+		return builder.Migration{
+			Filename: filename,
+			Data: builder.MigrationData{
+				Up:   builder.Section{Lines: []string{up}, Transaction: upTx, Present: true},
+				Down: builder.Section{Lines: []string{down}, Transaction: true, Present: true},
+			},
+		}
+	}
+	migrations := []builder.Migration{
+		migration("1-users.sql", true, "CREATE TABLE users (id int);", "DROP TABLE users;"),
+		migration("2-emails.sql", true, "CREATE TABLE emails (id int);", "DROP TABLE emails;"),
+		migration("3-index.sql", false, "CREATE INDEX CONCURRENTLY emails_idx ON emails (id);", "DROP INDEX emails_idx;"),
+		migration("4-phones.sql", true, "CREATE TABLE phones (id int);", "DROP TABLE phones;"),
+	}
+
+	squashed := builder.Squash(migrations)
+	require.Len(t, squashed, 3)
+	require.Equal(t, []int{0, 1}, squashed[0].Parts)
+	require.Equal(t, []int{2}, squashed[1].Parts)
+	require.Equal(t, []int{3}, squashed[2].Parts)
+
+	require.Equal(t, builder.Section{
+		Lines: []string{
+			"-- 1-users.sql", "CREATE TABLE users (id int);",
+			"-- 2-emails.sql", "CREATE TABLE emails (id int);",
+		},
+		Transaction: true,
+		Present:     true,
+	}, squashed[0].Data.Up)
+	require.Equal(t, builder.Section{
+		Lines: []string{
+			"-- 2-emails.sql", "DROP TABLE emails;",
+			"-- 1-users.sql", "DROP TABLE users;",
+		},
+		Transaction: true,
+		Present:     true,
+	}, squashed[0].Data.Down)
+	require.False(t, squashed[1].Data.Up.Transaction)
+}
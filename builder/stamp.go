@@ -0,0 +1,139 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Dialect string
+
+var (
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// dialectTextType is the type the source versions are compared as.
+var dialectTextType = map[Dialect]string{
+	DialectPostgres: "text",
+	DialectMySQL:    "char",
+	DialectSQLite:   "text",
+}
+
+// HistorySource is the library that filled the source history table.
+type HistorySource string
+
+var (
+	HistorySourceGoose      HistorySource = "goose"
+	HistorySourceSqlMigrate HistorySource = "sql-migrate"
+	HistorySourceDbmate     HistorySource = "dbmate"
+)
+
+// StampVersionsTable is the temporary table the version mapping is loaded to.
+const StampVersionsTable = "migradaptor_versions"
+
+func GetDialect(dialect string) (Dialect, error) {
+	dialect = strings.TrimSpace(dialect)
+	dialect = strings.ToLower(dialect)
+	if _, ok := dialectTextType[Dialect(dialect)]; !ok {
+		return *(new(Dialect)), ErrUnknownDialect
+	}
+	return Dialect(dialect), nil
+}
+
+func GetHistorySource(source string) (HistorySource, error) {
+	source = strings.TrimSpace(source)
+	source = strings.ToLower(source)
+	switch HistorySource(source) {
+	case HistorySourceGoose, HistorySourceSqlMigrate, HistorySourceDbmate:
+		return HistorySource(source), nil
+	default:
+		return *(new(HistorySource)), ErrUnknownHistorySource
+	}
+}
+
+// StampVersion maps the id a migration is recorded with in the source
+// history table to its converted version.
+type StampVersion struct {
+	SourceID string
+	Version  int64
+}
+
+// StampSQL generates the sql that creates the golang-migrate history table
+// and sets it to the greatest converted version applied in the source one.
+// A version mapped from several source ids, like a squashed migration, is
+// stamped dirty if only some of them are applied, so golang-migrate refuses
+// to run until the database is fixed.
+// dbmate's schema_migrations is renamed to schema_migrations_dbmate first
+// if it has the same name as the destination table.
+func StampSQL(source HistorySource, dialect Dialect, table string, versions []StampVersion) (string, error) {
+	textType, ok := dialectTextType[dialect]
+	if !ok {
+		return "", ErrUnknownDialect
+	}
+
+	var (
+		sb          strings.Builder
+		sourceTable string
+		applied     string
+	)
+	switch source {
+	case HistorySourceGoose:
+		sourceTable = "goose_db_version"
+		// the latest row of a version tells whether it's applied
+		applied = fmt.Sprintf("SELECT CAST(g.version_id AS %s) AS source_id FROM %s g "+
+			"WHERE g.is_applied AND g.id = (SELECT MAX(l.id) FROM %s l WHERE l.version_id = g.version_id)",
+			textType, sourceTable, sourceTable)
+	case HistorySourceSqlMigrate:
+		sourceTable = "gorp_migrations"
+		applied = fmt.Sprintf("SELECT id AS source_id FROM %s", sourceTable)
+	case HistorySourceDbmate:
+		sourceTable = "schema_migrations"
+		if sourceTable == table {
+			sourceTable += "_dbmate"
+			sb.WriteString(fmt.Sprintf("ALTER TABLE %s RENAME TO %s;\n", table, sourceTable))
+		}
+		applied = fmt.Sprintf("SELECT version AS source_id FROM %s", sourceTable)
+	default:
+		return "", ErrUnknownHistorySource
+	}
+
+	if hasSharedVersions(versions) {
+		sb.WriteString("-- a version shared by several source migrations is stamped dirty if only some of them are applied\n")
+	}
+	sb.WriteString(fmt.Sprintf("CREATE TEMPORARY TABLE %s (source_id varchar(255) NOT NULL, version bigint NOT NULL);\n", StampVersionsTable))
+	for i, v := range versions {
+		if i == 0 {
+			sb.WriteString(fmt.Sprintf("INSERT INTO %s (source_id, version) VALUES\n", StampVersionsTable))
+		}
+		sep := ",\n"
+		if i == len(versions)-1 {
+			sep = ";\n"
+		}
+		sb.WriteString(fmt.Sprintf("  ('%s', %d)%s", strings.ReplaceAll(v.SourceID, "'", "''"), v.Version, sep))
+	}
+	sb.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL);\n", table))
+	sb.WriteString(fmt.Sprintf("DELETE FROM %s;\n", table))
+	sb.WriteString(fmt.Sprintf("INSERT INTO %s (version, dirty)\n", table))
+	// the temporary table is referenced once, mysql can't reopen it
+	sb.WriteString(fmt.Sprintf("SELECT m.version, m.missing > 0 FROM (\n"+
+		"  SELECT v.version, SUM(CASE WHEN a.source_id IS NULL THEN 1 ELSE 0 END) AS missing FROM %s v\n"+
+		"  LEFT JOIN (%s) a ON a.source_id = v.source_id\n"+
+		"  GROUP BY v.version HAVING COUNT(a.source_id) > 0\n"+
+		"  ORDER BY v.version DESC LIMIT 1\n"+
+		") m;\n",
+		StampVersionsTable, applied))
+	sb.WriteString(fmt.Sprintf("DROP TABLE %s;\n", StampVersionsTable))
+	return sb.String(), nil
+}
+
+func hasSharedVersions(versions []StampVersion) bool {
+	seen := make(map[int64]struct{}, len(versions))
+	for _, v := range versions {
+		if _, ok := seen[v.Version]; ok {
+			return true
+		}
+		seen[v.Version] = struct{}{}
+	}
+	return false
+}
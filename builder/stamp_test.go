@@ -0,0 +1,68 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func TestStampSQL(t *testing.T) {
+	t.Parallel()
+	sql, err := builder.StampSQL(builder.HistorySourceDbmate, builder.DialectPostgres, "schema_migrations", []builder.StampVersion{
+		{SourceID: "20230101000000", Version: 20230101000000},
+		{SourceID: "20230101000000", Version: 20230101000001},
+		{SourceID: "o'clock", Version: 20230101000002},
+	})
+	require.NoError(t, err)
+	require.Equal(t, `ALTER TABLE schema_migrations RENAME TO schema_migrations_dbmate;
+CREATE TEMPORARY TABLE migradaptor_versions (source_id varchar(255) NOT NULL, version bigint NOT NULL);
+INSERT INTO migradaptor_versions (source_id, version) VALUES
+  ('20230101000000', 20230101000000),
+  ('20230101000000', 20230101000001),
+  ('o''clock', 20230101000002);
+CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL);
+DELETE FROM schema_migrations;
+INSERT INTO schema_migrations (version, dirty)
+SELECT m.version, m.missing > 0 FROM (
+  SELECT v.version, SUM(CASE WHEN a.source_id IS NULL THEN 1 ELSE 0 END) AS missing FROM migradaptor_versions v
+  LEFT JOIN (SELECT version AS source_id FROM schema_migrations_dbmate) a ON a.source_id = v.source_id
+  GROUP BY v.version HAVING COUNT(a.source_id) > 0
+  ORDER BY v.version DESC LIMIT 1
+) m;
+DROP TABLE migradaptor_versions;
+`, sql)
+}
+
+func TestStampSQL_Sources(t *testing.T) {
+	t.Parallel()
+	versions := []builder.StampVersion{{SourceID: "1", Version: 1}}
+
+	sql, err := builder.StampSQL(builder.HistorySourceGoose, builder.DialectMySQL, "schema_migrations", versions)
+	require.NoError(t, err)
+	require.Contains(t, sql, "SELECT CAST(g.version_id AS char) AS source_id FROM goose_db_version g WHERE g.is_applied")
+	require.NotContains(t, sql, "RENAME")
+
+	sql, err = builder.StampSQL(builder.HistorySourceSqlMigrate, builder.DialectSQLite, "migrations", versions)
+	require.NoError(t, err)
+	require.Contains(t, sql, "LEFT JOIN (SELECT id AS source_id FROM gorp_migrations)")
+	require.Contains(t, sql, "CREATE TABLE IF NOT EXISTS migrations ")
+
+	sql, err = builder.StampSQL(builder.HistorySourceDbmate, builder.DialectSQLite, "migrations", versions)
+	require.NoError(t, err)
+	require.Contains(t, sql, "FROM schema_migrations)")
+	require.NotContains(t, sql, "RENAME")
+
+	require.NotContains(t, sql, "stamped dirty")
+
+	sql, err = builder.StampSQL(builder.HistorySourceGoose, builder.DialectPostgres, "schema_migrations", []builder.StampVersion{
+		{SourceID: "1", Version: 2},
+		{SourceID: "2", Version: 2},
+	})
+	require.NoError(t, err)
+	require.Contains(t, sql, "-- a version shared by several source migrations is stamped dirty")
+
+	_, err = builder.StampSQL(builder.HistorySourceGoose, builder.Dialect("oracle"), "schema_migrations", versions)
+	require.ErrorIs(t, err, builder.ErrUnknownDialect)
+}
@@ -0,0 +1,115 @@
+package builder
+
+import (
+	"strings"
+)
+
+// SplitStatements splits sql lines into statements by semicolons, skipping
+// the ones inside quotes, dollar-quoted bodies and comments. Comments are
+// dropped, statements are trimmed and returned without the trailing semicolon.
+func SplitStatements(lines []string) []string {
+	statements, _ := splitStatements(lines)
+	return statements
+}
+
+// StatementLines returns the line where each statement of SplitStatements
+// starts, counting from 1.
+func StatementLines(lines []string) []int {
+	_, starts := splitStatements(lines)
+	return starts
+}
+
+func splitStatements(lines []string) ([]string, []int) {
+	src := strings.Join(lines, "\n")
+	statements := make([]string, 0)
+	starts := make([]int, 0)
+	current := strings.Builder{}
+	start := -1
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+			starts = append(starts, strings.Count(src[:start], "\n")+1)
+		}
+		current.Reset()
+		start = -1
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if start == -1 && !isStatementGap(src[i:]) {
+			start = i
+		}
+		switch {
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end == -1 {
+				i = len(src)
+				continue
+			}
+			i += end
+			current.WriteByte('\n')
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				i = len(src)
+				continue
+			}
+			i += end + 3
+			current.WriteByte(' ')
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(src[i+1:], c)
+			if end == -1 {
+				current.WriteString(src[i:])
+				i = len(src)
+				continue
+			}
+			current.WriteString(src[i : i+end+2])
+			i += end + 1
+		case c == '$':
+			tag := dollarQuoteTag(src[i:])
+			if tag == "" {
+				current.WriteByte(c)
+				continue
+			}
+			end := strings.Index(src[i+len(tag):], tag)
+			if end == -1 {
+				current.WriteString(src[i:])
+				i = len(src)
+				continue
+			}
+			current.WriteString(src[i : i+len(tag)+end+len(tag)])
+			i += len(tag) + end + len(tag) - 1
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements, starts
+}
+
+// isStatementGap reports whether s starts with whitespace, a comment or a
+// semicolon, that can't start a statement.
+func isStatementGap(s string) bool {
+	switch s[0] {
+	case ' ', '\t', '\n', '\r', ';':
+		return true
+	}
+	return strings.HasPrefix(s, "--") || strings.HasPrefix(s, "/*")
+}
+
+// dollarQuoteTag returns $tag$ or $$ if s starts with it.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package builder_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func Test_SplitStatements(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"several statements on one line",
+			`CREATE TABLE companies (id int, title string);CREATE INDEX companies_title_idx on companies (title);`,
+			[]string{
+				"CREATE TABLE companies (id int, title string)",
+				"CREATE INDEX companies_title_idx on companies (title)",
+			},
+		},
+		{
+			"comments and quotes",
+			`-- create table; with comment
+INSERT INTO t (v) VALUES ('a;b', "c;d"); /* block; comment */
+SELECT 1`,
+			[]string{
+				`INSERT INTO t (v) VALUES ('a;b', "c;d")`,
+				"SELECT 1",
+			},
+		},
+		{
+			"dollar quoted function",
+			`CREATE OR REPLACE FUNCTION do_something()
+returns void AS $body$
+BEGIN
+  PERFORM 1;
+END;
+$body$
+language plpgsql;`,
+			[]string{
+				`CREATE OR REPLACE FUNCTION do_something()
+returns void AS $body$
+BEGIN
+  PERFORM 1;
+END;
+$body$
+language plpgsql`,
+			},
+		},
+		{
+			"only comments",
+			`-- nothing here
+`,
+			[]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			have := builder.SplitStatements(strings.Split(tc.input, "\n"))
+			require.Equal(t, tc.expected, have)
+		})
+	}
+}
+
+func Test_StatementLines(t *testing.T) {
+	input := `BEGIN;
+-- create table; with comment
+CREATE TABLE t (
+  v text
+); /* block; comment */ INSERT INTO t (v) VALUES ('a;
+b');
+
+COMMIT;`
+	have := builder.StatementLines(strings.Split(input, "\n"))
+	require.Equal(t, []int{1, 3, 5, 8}, have)
+}
@@ -0,0 +1,147 @@
+package builder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Versioning is the way the destination versions are given to the source migrations.
+type Versioning string
+
+var (
+	// VersioningBump keeps the source versions, a version that is not greater
+	// than the previous one becomes the previous one + 1.
+	VersioningBump Versioning = "bump"
+	// VersioningPreserve keeps the source versions and fails on collisions.
+	VersioningPreserve Versioning = "preserve"
+	// VersioningSequential numbers the migrations 000001, 000002, ...
+	VersioningSequential Versioning = "sequential"
+	// VersioningTimestamp regenerates UTC timestamps spaced evenly between
+	// the first and the last source timestamps.
+	VersioningTimestamp Versioning = "timestamp"
+	// VersioningHybrid keeps the sequential source versions and renumbers the
+	// timestamp ones to follow them, the way goose fix does.
+	VersioningHybrid Versioning = "hybrid"
+)
+
+const (
+	timestampVersionLayout = "20060102150405"
+	sequentialVersionWidth = 6
+)
+
+func GetVersioning(versioning string) (Versioning, error) {
+	versioning = strings.TrimSpace(versioning)
+	versioning = strings.ToLower(versioning)
+	switch Versioning(versioning) {
+	case "":
+		return VersioningBump, nil
+	case VersioningBump, VersioningPreserve, VersioningSequential, VersioningTimestamp, VersioningHybrid:
+		return Versioning(versioning), nil
+	default:
+		return *(new(Versioning)), ErrUnknownVersioning
+	}
+}
+
+// FormatVersion returns the version as it's written in the destination filenames.
+func (v Versioning) FormatVersion(version int64) string {
+	if v == VersioningSequential {
+		return fmt.Sprintf("%0*d", sequentialVersionWidth, version)
+	}
+	return strconv.FormatInt(version, 10)
+}
+
+// AssignVersions returns the destination versions of the source ones,
+// given in the order the migrations are applied.
+func AssignVersions(versions []int64, v Versioning) ([]int64, error) {
+	assigned := make([]int64, len(versions))
+	switch v {
+	case VersioningBump, "":
+		maxVersion := int64(0)
+		for i, version := range versions {
+			assigned[i] = NextVersion(version, maxVersion)
+			maxVersion = assigned[i]
+		}
+	case VersioningPreserve:
+		copy(assigned, versions)
+	case VersioningSequential:
+		for i := range versions {
+			assigned[i] = int64(i + 1)
+		}
+	case VersioningTimestamp:
+		assigned = spreadTimestamps(versions)
+	case VersioningHybrid:
+		maxVersion := int64(0)
+		for i, version := range versions {
+			if IsTimestampVersion(version) {
+				version = maxVersion + 1
+			}
+			assigned[i] = version
+			if version > maxVersion {
+				maxVersion = version
+			}
+		}
+	default:
+		return nil, ErrUnknownVersioning
+	}
+	return assigned, ValidateVersions(assigned)
+}
+
+// ValidateVersions checks the versions are unique and strictly increasing.
+func ValidateVersions(versions []int64) error {
+	for i := 1; i < len(versions); i++ {
+		switch {
+		case versions[i] == versions[i-1]:
+			return fmt.Errorf("%w: %d is used twice", ErrVersionCollision, versions[i])
+		case versions[i] < versions[i-1]:
+			return fmt.Errorf("%w: %d follows %d", ErrVersionCollision, versions[i], versions[i-1])
+		}
+	}
+	return nil
+}
+
+// IsTimestampVersion reports whether the version is a YYYYMMDDHHMMSS timestamp.
+func IsTimestampVersion(version int64) bool {
+	_, ok := parseTimestampVersion(version)
+	return ok
+}
+
+func parseTimestampVersion(version int64) (time.Time, bool) {
+	t, err := time.Parse(timestampVersionLayout, strconv.FormatInt(version, 10))
+	return t, err == nil
+}
+
+// spreadTimestamps spaces the versions evenly, at least a second apart,
+// from the first to the last source timestamp. The Unix epoch is the start
+// if there are no timestamps among the source versions.
+func spreadTimestamps(versions []int64) []int64 {
+	var first, last time.Time
+	for _, version := range versions {
+		t, ok := parseTimestampVersion(version)
+		if !ok {
+			continue
+		}
+		if first.IsZero() {
+			first = t
+		}
+		last = t
+	}
+	if first.IsZero() {
+		first = time.Unix(0, 0).UTC()
+		last = first
+	}
+
+	step := time.Second
+	if len(versions) > 1 && last.After(first) {
+		if s := last.Sub(first) / time.Duration(len(versions)-1); s > step {
+			step = s.Truncate(time.Second)
+		}
+	}
+	assigned := make([]int64, len(versions))
+	for i := range versions {
+		version, _ := strconv.ParseInt(first.Add(time.Duration(i)*step).Format(timestampVersionLayout), 10, 64)
+		assigned[i] = version
+	}
+	return assigned
+}
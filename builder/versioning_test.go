@@ -0,0 +1,99 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func TestAssignVersions(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name       string
+		versioning builder.Versioning
+		versions   []int64
+		expected   []int64
+		err        error
+	}{
+		{
+			name:       "bump",
+			versioning: builder.VersioningBump,
+			versions:   []int64{1, 1, 2, 5},
+			expected:   []int64{1, 2, 3, 5},
+		},
+		{
+			name:       "preserve",
+			versioning: builder.VersioningPreserve,
+			versions:   []int64{1, 2, 5},
+			expected:   []int64{1, 2, 5},
+		},
+		{
+			name:       "preserve collision",
+			versioning: builder.VersioningPreserve,
+			versions:   []int64{1, 2, 2},
+			err:        builder.ErrVersionCollision,
+		},
+		{
+			name:       "sequential",
+			versioning: builder.VersioningSequential,
+			versions:   []int64{20230101000000, 20230101000000, 7},
+			expected:   []int64{1, 2, 3},
+		},
+		{
+			name:       "timestamp",
+			versioning: builder.VersioningTimestamp,
+			versions:   []int64{20230101000000, 20230101000000, 20230101000100},
+			expected:   []int64{20230101000000, 20230101000030, 20230101000100},
+		},
+		{
+			name:       "timestamp without source timestamps",
+			versioning: builder.VersioningTimestamp,
+			versions:   []int64{1, 2},
+			expected:   []int64{19700101000000, 19700101000001},
+		},
+		{
+			name:       "hybrid",
+			versioning: builder.VersioningHybrid,
+			versions:   []int64{1, 2, 20230101000000, 20230102000000},
+			expected:   []int64{1, 2, 3, 4},
+		},
+		{
+			name:       "hybrid collision",
+			versioning: builder.VersioningHybrid,
+			versions:   []int64{1, 20230101000000, 2},
+			err:        builder.ErrVersionCollision,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			versions, err := builder.AssignVersions(tc.versions, tc.versioning)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, versions)
+		})
+	}
+}
+
+func TestVersioning_FormatVersion(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "000012", builder.VersioningSequential.FormatVersion(12))
+	require.Equal(t, "12", builder.VersioningBump.FormatVersion(12))
+}
+
+func TestParseFilename_LongVersion(t *testing.T) {
+	t.Parallel()
+	version, name, err := builder.ParseFilename("2023010100000012-users.sql")
+	require.NoError(t, err)
+	require.Equal(t, int64(2023010100000012), version)
+	require.Equal(t, "users", name)
+
+	_, _, err = builder.ParseFilename("99999999999999999999-users.sql")
+	require.Error(t, err)
+}
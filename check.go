@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+var checkStatuses = map[builder.FileStatus]string{
+	builder.FileStatusNew:     "missing",
+	builder.FileStatusRemoved: "extra",
+	builder.FileStatusChanged: "changed",
+}
+
+func runCheck(args []string) {
+	var (
+		opts     convertOptions
+		showDiff bool
+	)
+	fs := newFlagSet("check", "[options]",
+		"Converts the source migrations in memory and fails if the destination folder is out of sync.\n"+
+			"If the destination has a manifest, files not produced by the converter are ignored.")
+	opts.register(fs)
+	fs.BoolVar(&showDiff, "diff", false, "print the unified diff of the files out of sync")
+	parseFlags(fs, args)
+	checkReportStdout("check")
+
+	dstMigrPath, convertOpts := opts.options()
+	result := convert(convertOpts)
+	changes, err := builder.CompareDir(dstMigrPath, result.Files)
+	if err != nil {
+		fatalf("read dest migrations folder error: %s", err.Error())
+	}
+	manifest, err := builder.ReadManifest(dstMigrPath)
+	if err != nil {
+		fatalf("read manifest error: %s", err.Error())
+	}
+
+	outOfSync := make([]builder.FileChange, 0)
+	unowned := make([]string, 0)
+	for _, change := range changes {
+		status, ok := checkStatuses[change.Status]
+		if !ok {
+			continue
+		}
+		if _, owned := manifest.Files[change.Name]; change.Status == builder.FileStatusRemoved && len(manifest.Files) != 0 && !owned {
+			unowned = append(unowned, change.Name)
+			continue
+		}
+		fmt.Printf("%-8s %s\n", status, change.Name)
+		outOfSync = append(outOfSync, change)
+	}
+	// files not produced by the converter must not reuse its versions
+	clashes := builder.VersionClashes(unowned, result.Files)
+	for _, name := range clashes {
+		fmt.Printf("%-8s %s\n", "clash", name)
+	}
+	if showDiff {
+		for _, change := range outOfSync {
+			diff, err := builder.UnifiedDiff(change)
+			if err != nil {
+				fatalf("diff error: %s", err.Error())
+			}
+			fmt.Print(diff)
+		}
+	}
+	if len(outOfSync) != 0 || len(clashes) != 0 {
+		srcMigrPaths := opts.srcMigrPaths.String()
+		if srcMigrPaths == "" {
+			srcMigrPaths = "src"
+		}
+		fatalf("%d files out of sync with %s", len(outOfSync)+len(clashes), srcMigrPaths)
+	}
+}
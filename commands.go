@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+const (
+	exitOK = 0
+	// exitFailed is returned if the command failed or found problems:
+	// lint issues, files out of sync or failed conversions.
+	exitFailed = 1
+	// exitUsage is returned for invalid flags, config or arguments.
+	exitUsage = 2
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+// defaultCommand runs if there are no arguments or they start with a flag.
+const defaultCommand = "convert"
+
+var commands = []command{
+	{name: "convert", summary: "convert the source migrations to the destination format", run: runConvert},
+	{name: "detect", summary: "print the format of each source migration", run: runDetect},
+	{name: "lint", summary: "check the source migrations for risky statements", run: runLint},
+	{name: "check", summary: "fail if the destination folder is out of sync with the sources", run: runCheck},
+	{name: "stamp", summary: "generate the sql that fills the golang-migrate history table", run: runStamp},
+	{name: "squash", summary: "convert folding a range of versions into one migration", run: runSquash},
+	{name: "new", summary: "create an empty migration in the destination folder", run: runNew},
+}
+
+func main() {
+	defer finish()
+	args := os.Args[1:]
+	if len(args) == 0 {
+		runCommand(defaultCommand, args)
+		return
+	}
+	switch args[0] {
+	case "-version", "--version", "version":
+		fmt.Println(GetVersion())
+		return
+	case "-help", "--help", "-h", "help":
+		if len(args) > 1 {
+			runCommand(args[1], []string{"-help"})
+			return
+		}
+		printHelp(os.Stdout)
+		return
+	}
+	if args[0][0] == '-' {
+		runCommand(defaultCommand, args)
+		return
+	}
+	runCommand(args[0], args[1:])
+}
+
+func runCommand(name string, args []string) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cmd.run(args)
+			return
+		}
+	}
+	usagef("unknown command %q, run migradaptor help for the list of commands", name)
+}
+
+// printHelp prints the commands, their options are printed by the flag sets.
+func printHelp(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Usage: migradaptor <command> [options]\n\n"+
+		"Migrate your sql migrations files between different lib formats.\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(w, "\nRun migradaptor <command> -help for the options of a command.\n"+
+		"Options given without a command, or no arguments, run %s.\n", defaultCommand)
+}
+
+// newFlagSet returns the flags of the command with the usage generated
+// from their definitions.
+func newFlagSet(name, synopsis, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: migradaptor %s %s\n\n%s\n\nOptions:\n", name, synopsis, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// usagef reports an invalid input and exits with exitUsage.
+func usagef(format string, args ...any) {
+	report(errorDiagnostic(format, args...))
+	exit(exitUsage)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+var (
+	// configPath is the -config file, builder.ConfigFilename is read if it's not set.
+	configPath string
+	// configVars are the placeholder values of the config.
+	configVars map[string]string
+)
+
+// registerConfig adds the -config flag to the command flags.
+func registerConfig(fs *flag.FlagSet) {
+	fs.StringVar(&configPath, "config", "", "config file with the options of the commands, "+builder.ConfigFilename+" is read if it exists; flags override it")
+}
+
+// configValue is a flag value given in the config.
+type configValue struct {
+	name  string
+	value string
+}
+
+// parseFlags parses the command flags and fills the ones that are not set
+// with the config values.
+func parseFlags(flags *flag.FlagSet, args []string) {
+	_ = flags.Parse(args)
+	config, ok := readConfig()
+	if !ok {
+		return
+	}
+	configVars = config.Vars
+
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if src := flags.Lookup("src"); src != nil && !set["src"] && !isSingleSource(config.Src) {
+		// -src of the other commands takes a single folder
+		if _, ok := src.Value.(*sourcePaths); !ok {
+			usagef("config error: src: %s takes a single source folder without a prefix or a glob", flags.Name())
+		}
+	}
+	for _, v := range configValues(config, flags.Name()) {
+		if set[v.name] || flags.Lookup(v.name) == nil {
+			continue
+		}
+		if err := flags.Set(v.name, v.value); err != nil {
+			fatalf("config error: %s: %s", v.name, err.Error())
+		}
+	}
+}
+
+func isSingleSource(src []string) bool {
+	return len(src) <= 1 && !strings.ContainsAny(strings.Join(src, ""), "=*?[")
+}
+
+// readConfig returns false if there is no config file.
+func readConfig() (builder.Config, bool) {
+	path := configPath
+	if path == "" {
+		path = builder.ConfigFilename
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) && configPath == "" {
+		return builder.Config{}, false
+	}
+	if err != nil {
+		usagef("open config error: %s", err.Error())
+	}
+	config, err := builder.ReadConfig(f)
+	_ = f.Close()
+	if err != nil {
+		usagef("read config error: %s", err.Error())
+	}
+	if err := config.Validate(); err != nil {
+		usagef("validate config error: %s", err.Error())
+	}
+	return config, true
+}
+
+// configValues returns the flag values of the config, the ones of the
+// command section included.
+func configValues(c builder.Config, command string) []configValue {
+	values := make([]configValue, 0)
+	addString := func(name, value string) {
+		if value != "" {
+			values = append(values, configValue{name: name, value: value})
+		}
+	}
+	addBool := func(name string, value bool) {
+		if value {
+			values = append(values, configValue{name: name, value: "true"})
+		}
+	}
+	addList := func(name string, list []string) {
+		for _, value := range list {
+			addString(name, value)
+		}
+	}
+
+	addList("src", c.Src)
+	addString("dst", c.Dst)
+	addString("dst-lib", c.DstLib)
+	addString("placeholders", c.Placeholders)
+	addString("vars-file", c.VarsFile)
+	addBool("gen-down", c.GenDown)
+	addString("versioning", c.Versioning)
+	addBool("git-versions", c.GitVersions)
+	addBool("recursive", c.Recursive)
+	addList("include", c.Include)
+	addList("exclude", c.Exclude)
+	addBool("continue-on-error", c.ContinueOnError)
+	addBool("write-valid", c.WriteValid)
+	addString("diagnostics", c.Diagnostics)
+	addString("report", c.Report)
+
+	switch command {
+	case "lint":
+		addString("fail-on", c.Lint.FailOn)
+		for rule, severity := range c.Lint.Rules {
+			addString("rule", rule+"="+severity)
+		}
+	case "stamp":
+		addString("from", c.Stamp.From)
+		addString("dialect", c.Stamp.Dialect)
+		addString("table", c.Stamp.Table)
+		addString("out", c.Stamp.Out)
+	case "squash":
+		if c.Squash.From != 0 || c.Squash.To != 0 {
+			addString("from", strconv.FormatInt(c.Squash.From, 10))
+			addString("to", strconv.FormatInt(c.Squash.To, 10))
+		}
+		addString("name", c.Squash.Name)
+	}
+	return values
+}
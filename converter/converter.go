@@ -0,0 +1,566 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+// SourceDir is one of several source folders merged into one destination.
+type SourceDir struct {
+	// Name identifies the folder in the warnings and the manifest, e.g. its path.
+	Name string
+	FS   fs.FS
+	// Prefix is prepended to the migration names.
+	Prefix string
+	// Versions overrides the source versions parsed from the filenames.
+	Versions map[string]int64
+}
+
+type Options struct {
+	// Source is the folder with the source migrations.
+	Source fs.FS
+	// Sources are merged with Source into one sequence ordered by version.
+	Sources []SourceDir
+	// Destination receives the converted files, nothing is written if it's nil.
+	Destination  Sink
+	DstType      builder.DstType
+	Placeholders builder.Placeholders
+	// GenerateDown fills empty down sections with the inverse of the up ones.
+	GenerateDown bool
+	// Versioning defaults to builder.VersioningBump.
+	Versioning builder.Versioning
+	// Versions overrides the source versions parsed from the filenames,
+	// e.g. with builder.GitVersions.
+	Versions map[string]int64
+	// Recursive walks the nested folders of the sources.
+	Recursive bool
+	// Include and Exclude are path.Match patterns of the files to convert.
+	// Patterns without a slash match the file name, others the path in the
+	// source. DefaultExcludes are always excluded.
+	Include []string
+	Exclude []string
+	// Squash folds a range of the converted migrations.
+	Squash *SquashRange
+	// ContinueOnError converts the other files if one can't be read or
+	// parsed, the failures are returned together in Result.Failures.
+	ContinueOnError bool
+	// WriteValid writes the files converted in ContinueOnError mode even if
+	// some failed, nothing is written otherwise.
+	WriteValid bool
+}
+
+type Migration struct {
+	// Source is the file the migration is read from.
+	Source string
+	// SourceDir is the name of the source folder, set if there are several.
+	SourceDir       string
+	OriginalVersion int64
+	Version         int64
+	Name            string
+	Format          builder.SourceFormat
+	Files           []string
+	UpTransaction   bool
+	DownTransaction bool
+	// Warnings are the warnings of Result.Warnings raised for the migration.
+	Warnings []string
+	// Squashed are the migrations folded into a squashed one.
+	Squashed []Migration
+}
+
+type Result struct {
+	Migrations []Migration
+	Files      []builder.File
+	// Warnings are the messages of the warning diagnostics.
+	Warnings []string
+	// Diagnostics are raised while loading, converting and writing, skipped
+	// files are reported as info diagnostics.
+	Diagnostics builder.Diagnostics
+	// Skipped are the source files that are not converted.
+	Skipped []Skipped
+	// Failures are the error diagnostics of the files that failed in
+	// ContinueOnError mode, they are among Diagnostics too.
+	Failures builder.Diagnostics
+}
+
+// Convert converts all the source migrations in memory first, so nothing is
+// written to the destination if any of them fails.
+func Convert(ctx context.Context, opts Options) (result Result, err error) {
+	defer recoverError(&err)
+	result = Result{Skipped: make([]Skipped, 0)}
+	defer func() {
+		result.Warnings = result.Diagnostics.Messages(builder.SeverityWarning)
+		if d := (builder.Diagnostic{}); len(result.Failures) == 0 && errors.As(err, &d) {
+			result.Diagnostics = append(result.Diagnostics, d)
+		}
+	}()
+	migrations, diags, err := loadSources(ctx, opts, &result.Skipped)
+	sort.SliceStable(result.Skipped, func(i, j int) bool {
+		return result.Skipped[i].Name < result.Skipped[j].Name
+	})
+	for _, s := range result.Skipped {
+		result.Diagnostics.Add(builder.SeverityInfo, builder.DiagnosticSkipped, s.Name, 0, "%s", s.Reason)
+	}
+	result.Diagnostics = append(result.Diagnostics, diags...)
+	if err != nil {
+		return result, err
+	}
+
+	versions, err := AssignVersions(migrations, opts)
+	if err != nil {
+		return result, err
+	}
+
+	converted := make([]Migration, 0, len(migrations))
+	data := make([]builder.MigrationData, 0, len(migrations))
+	for i, m := range migrations {
+		diags := m.Diagnostics
+		file := sourceName(m)
+		if opts.GenerateDown {
+			if irreversible, ok := m.Data.GenerateDown(); ok {
+				diags.Add(builder.SeverityWarning, builder.DiagnosticGeneratedDown, file, 0,
+					"generated down section for %s", m.Filename)
+				for _, statement := range irreversible {
+					diags.Add(builder.SeverityWarning, builder.DiagnosticIrreversible, file, 0,
+						"irreversible statement in %s: %s", m.Filename, statement)
+				}
+			}
+		}
+
+		timestamp := versions[i]
+		if timestamp != m.Version {
+			diags.Add(builder.SeverityWarning, builder.DiagnosticVersionChanged, file, 0,
+				"version %d of %s is changed to %d", m.Version, m.Filename, timestamp)
+		}
+		result.Diagnostics = append(result.Diagnostics, diags[len(m.Diagnostics):]...)
+
+		converted = append(converted, Migration{
+			Source:          m.Filename,
+			SourceDir:       m.Dir,
+			OriginalVersion: m.Version,
+			Version:         timestamp,
+			Name:            m.Name,
+			Format:          m.Format,
+			UpTransaction:   m.Data.Up.Transaction,
+			DownTransaction: m.Data.Down.Transaction,
+			Warnings:        diags.Messages(builder.SeverityWarning),
+		})
+		data = append(data, m.Data)
+	}
+	if opts.Squash != nil {
+		converted, data, err = squash(*opts.Squash, converted, data)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	for i, m := range converted {
+		var upMigr, downMigr []string
+		switch opts.DstType {
+		default:
+			upMigr, downMigr = data[i].Build()
+		}
+
+		files := builder.MigrationFiles(opts.Versioning.FormatVersion(m.Version), m.Name, upMigr, downMigr)
+		for _, f := range files {
+			m.Files = append(m.Files, f.Name)
+		}
+		result.Migrations = append(result.Migrations, m)
+		result.Files = append(result.Files, files...)
+	}
+
+	result.Failures = result.Diagnostics.Failures()
+	if opts.Destination == nil || (len(result.Failures) != 0 && !opts.WriteValid) {
+		return result, result.Failures.Err()
+	}
+	for _, f := range result.Files {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := opts.Destination.WriteFile(f.Name, f.Content); err != nil {
+			err = builder.FileError(builder.DiagnosticWriteFailed, f.Name, err)
+			if err := fail(err, opts.ContinueOnError, &result.Diagnostics); err != nil {
+				return result, err
+			}
+		}
+	}
+	result.Failures = result.Diagnostics.Failures()
+	return result, result.Failures.Err()
+}
+
+// fail records the error of a file among the diagnostics in ContinueOnError
+// mode, otherwise it returns the error to stop at.
+func fail(err error, continueOnError bool, diags *builder.Diagnostics) error {
+	if !continueOnError {
+		return err
+	}
+	var d builder.Diagnostic
+	if !errors.As(err, &d) {
+		d = builder.Diagnostic{Severity: builder.SeverityError, Message: err.Error(), Err: err}
+	}
+	*diags = append(*diags, d)
+	return nil
+}
+
+// AssignVersions returns the destination versions of the loaded migrations.
+func AssignVersions(migrations []builder.Migration, opts Options) ([]int64, error) {
+	dirVersions := make(map[string]map[string]int64)
+	for _, dir := range opts.sources() {
+		dirVersions[dir.Name] = dir.Versions
+	}
+	sourceVersions := make([]int64, 0, len(migrations))
+	for _, m := range migrations {
+		version, ok := dirVersions[m.Dir][m.Filename]
+		if !ok {
+			version = m.Version
+		}
+		sourceVersions = append(sourceVersions, version)
+	}
+	versions, err := builder.AssignVersions(sourceVersions, opts.Versioning)
+	if err != nil {
+		for i := 1; i < len(versions); i++ {
+			if versions[i] <= versions[i-1] {
+				return nil, errors.Wrapf(err, "assign versions: %s and %s",
+					sourceName(migrations[i-1]), sourceName(migrations[i]))
+			}
+		}
+		return nil, errors.Wrap(err, "assign versions")
+	}
+	return versions, nil
+}
+
+func sourceName(m builder.Migration) string {
+	return path.Join(m.Dir, m.Filename)
+}
+
+// sources returns Source and Sources, names are cleared if there is only one.
+func (o Options) sources() []SourceDir {
+	sources := make([]SourceDir, 0, len(o.Sources)+1)
+	if o.Source != nil {
+		sources = append(sources, SourceDir{FS: o.Source, Versions: o.Versions})
+	}
+	sources = append(sources, o.Sources...)
+	if len(sources) == 1 {
+		sources[0].Name = ""
+	}
+	return sources
+}
+
+// LoadMigrations reads and parses the source migrations in the order
+// they have to be applied. Migrations of several sources are ordered by
+// their source versions, keeping the order of each source.
+func LoadMigrations(ctx context.Context, opts Options) (migrations []builder.Migration, diags builder.Diagnostics, err error) {
+	defer recoverError(&err)
+	skipped := make([]Skipped, 0)
+	return loadSources(ctx, opts, &skipped)
+}
+
+// recoverError turns a panic of the parsers into an error, so library callers
+// never see one.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: %v", builder.ErrInternal, r)
+	}
+}
+
+func loadSources(ctx context.Context, opts Options, skipped *[]Skipped) ([]builder.Migration, builder.Diagnostics, error) {
+	diags := make(builder.Diagnostics, 0)
+	sources := opts.sources()
+	if len(sources) == 1 {
+		return loadDir(ctx, opts, sources[0], skipped)
+	}
+
+	names := make(map[string]struct{}, len(sources))
+	migrations := make([]builder.Migration, 0)
+	for _, dir := range sources {
+		if _, ok := names[dir.Name]; ok {
+			return nil, diags, fmt.Errorf("%w: %q", builder.ErrDuplicateSourceName, dir.Name)
+		}
+		names[dir.Name] = struct{}{}
+		dirMigrations, dirDiags, err := loadDir(ctx, opts, dir, skipped)
+		diags = append(diags, dirDiags...)
+		if err != nil {
+			return nil, diags, errors.Wrapf(err, "source %s", dir.Name)
+		}
+		migrations = append(migrations, dirMigrations...)
+	}
+	sortByVersion(migrations)
+	return migrations, diags, nil
+}
+
+func loadDir(ctx context.Context, opts Options, dir SourceDir, skipped *[]Skipped) ([]builder.Migration, builder.Diagnostics, error) {
+	read := len(*skipped)
+	migrations, diags, err := loadMigrations(ctx, dir.FS, opts, skipped)
+	for i := range migrations {
+		migrations[i].Dir = dir.Name
+		if dir.Prefix != "" {
+			migrations[i].Name = dir.Prefix + "_" + migrations[i].Name
+		}
+	}
+	for i := read; i < len(*skipped); i++ {
+		(*skipped)[i].Name = path.Join(dir.Name, (*skipped)[i].Name)
+	}
+	for i := range diags {
+		if diags[i].File != "" {
+			diags[i].File = path.Join(dir.Name, diags[i].File)
+		}
+	}
+	if d, ok := err.(builder.Diagnostic); ok {
+		d.File = path.Join(dir.Name, d.File)
+		err = d
+	}
+	return migrations, diags, err
+}
+
+// loadMigrations loads each folder found in fsys the same way, the migrations
+// are ordered by their source versions rather than by their file names.
+func loadMigrations(ctx context.Context, fsys fs.FS, opts Options, skipped *[]Skipped) ([]builder.Migration, builder.Diagnostics, error) {
+	diags := make(builder.Diagnostics, 0)
+	folders, err := discover(fsys, opts, skipped)
+	if err != nil {
+		return nil, diags, err
+	}
+
+	migrations := make([]builder.Migration, 0)
+	for _, folder := range folders {
+		var (
+			folderMigrations []builder.Migration
+			folderDiags      builder.Diagnostics
+		)
+		if builder.IsGoPgLayout(folder.filenames) {
+			folderMigrations, folderDiags, err = loadGoPgMigrations(ctx, fsys, folder, opts, skipped)
+		} else {
+			folderMigrations, folderDiags, err = loadFolder(ctx, fsys, folder, opts, skipped)
+		}
+		diags = append(diags, folderDiags...)
+		if err != nil {
+			return nil, diags, err
+		}
+		migrations = append(migrations, folderMigrations...)
+	}
+	sortByVersion(migrations)
+	return migrations, diags, nil
+}
+
+func sortByVersion(migrations []builder.Migration) {
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+func loadFolder(ctx context.Context, fsys fs.FS, folder folder, opts Options, skipped *[]Skipped) ([]builder.Migration, builder.Diagnostics, error) {
+	diags := make(builder.Diagnostics, 0)
+	migrations := make([]builder.Migration, 0, len(folder.filenames))
+	for _, basename := range folder.filenames {
+		if err := ctx.Err(); err != nil {
+			return nil, diags, err
+		}
+		var (
+			filename  = folder.path(basename)
+			lines     []string
+			numbers   []int
+			timestamp int64
+			name      string
+			format    builder.SourceFormat
+			read      = len(diags)
+			err       error
+		)
+		switch {
+		case builder.IsSqlMigrationFile(basename):
+			lines, numbers, err = readLines(fsys, filename, opts.Placeholders, &diags)
+			if err != nil {
+				if err := fail(err, opts.ContinueOnError, &diags); err != nil {
+					return nil, diags, err
+				}
+				continue
+			}
+			format = builder.DetectSourceFormat(lines)
+			timestamp, name, err = builder.ParseFilename(basename)
+		case builder.IsGoMigrationFile(basename):
+			var ok bool
+			lines, ok, err = readGooseGoMigration(fsys, filename, &diags)
+			if err != nil {
+				if err := fail(err, opts.ContinueOnError, &diags); err != nil {
+					return nil, diags, err
+				}
+				continue
+			}
+			if !ok {
+				*skipped = append(*skipped, Skipped{Name: filename, Reason: "no goose migrations are registered"})
+				continue
+			}
+			format = builder.SourceFormatGooseGo
+			timestamp, name, err = builder.ParseGoFilename(basename)
+		default:
+			*skipped = append(*skipped, Skipped{Name: filename, Reason: "not a migration file"})
+			continue
+		}
+		if err != nil {
+			err = builder.FileError(builder.DiagnosticInvalidFilename, filename, err)
+			if err := fail(err, opts.ContinueOnError, &diags); err != nil {
+				return nil, diags, err
+			}
+			continue
+		}
+
+		migrations = append(migrations, builder.Migration{
+			Filename:    filename,
+			Version:     timestamp,
+			Name:        name,
+			Data:        builder.ParseNumberedMigrationData(lines, numbers),
+			Format:      format,
+			Diagnostics: append(builder.Diagnostics(nil), diags[read:]...),
+		})
+	}
+	return migrations, diags, nil
+}
+
+func loadGoPgMigrations(ctx context.Context, fsys fs.FS, folder folder, opts Options, skipped *[]Skipped) ([]builder.Migration, builder.Diagnostics, error) {
+	diags := make(builder.Diagnostics, 0)
+	gopgMigrations, goOnly, err := builder.CollectGoPgMigrations(folder.filenames)
+	if err != nil {
+		return nil, diags, errors.Wrap(err, "collect go-pg migrations")
+	}
+	for _, version := range goOnly {
+		diags.Add(builder.SeverityWarning, builder.DiagnosticGoPgGoOnly, folder.dir, 0,
+			"skip go-pg migration %d: registered in Go only", version)
+	}
+
+	collected := make(map[string]struct{}, len(folder.filenames))
+	migrations := make([]builder.Migration, 0, len(gopgMigrations))
+	for _, m := range gopgMigrations {
+		if err := ctx.Err(); err != nil {
+			return nil, diags, err
+		}
+		var (
+			upLines, downLines     []string
+			upNumbers, downNumbers []int
+			read                   = len(diags)
+			err                    error
+		)
+		collected[m.UpFilename] = struct{}{}
+		collected[m.DownFilename] = struct{}{}
+		if m.UpFilename != "" {
+			upLines, upNumbers, err = readLines(fsys, folder.path(m.UpFilename), opts.Placeholders, &diags)
+		}
+		if err == nil && m.DownFilename != "" {
+			downLines, downNumbers, err = readLines(fsys, folder.path(m.DownFilename), opts.Placeholders, &diags)
+		}
+		if err != nil {
+			if err := fail(err, opts.ContinueOnError, &diags); err != nil {
+				return nil, diags, err
+			}
+			continue
+		}
+		filename := m.UpFilename
+		if filename == "" {
+			filename = m.DownFilename
+		}
+		data := builder.ParseGoPgMigrationData(m, upLines, downLines)
+		// the diagnostics of the migration point to one of its files
+		if filename == m.UpFilename {
+			data.Up.LineNumbers = upNumbers
+		} else {
+			data.Down.LineNumbers = downNumbers
+		}
+		migrations = append(migrations, builder.Migration{
+			Filename:    folder.path(filename),
+			Version:     m.Version,
+			Name:        m.Name,
+			Data:        data,
+			Format:      builder.SourceFormatGoPg,
+			Diagnostics: append(builder.Diagnostics(nil), diags[read:]...),
+		})
+	}
+	for _, filename := range folder.filenames {
+		if _, ok := collected[filename]; !ok && !builder.IsGoMigrationFile(filename) {
+			*skipped = append(*skipped, Skipped{Name: folder.path(filename), Reason: "not a go-pg migration file"})
+		}
+	}
+	return migrations, diags, nil
+}
+
+// readLines returns the processed lines of the file with their numbers in it.
+func readLines(fsys fs.FS, filename string, placeholders builder.Placeholders, diags *builder.Diagnostics) ([]string, []int, error) {
+	lines, err := builder.ReadFSFileLines(fsys, filename)
+	if err != nil {
+		return nil, nil, builder.FileError(builder.DiagnosticReadFailed, filename, err)
+	}
+	source := lines
+	lines, numbers, unresolved := builder.ProcessPlaceholderLines(lines, placeholders)
+	for _, name := range unresolved {
+		diags.Add(builder.SeverityWarning, builder.DiagnosticUnresolvedPlaceholder, filename, placeholderLine(source, name),
+			"unresolved placeholder ${%s} in %s", name, filename)
+	}
+	return lines, numbers, nil
+}
+
+// placeholderLine returns the first line referencing the placeholder, 0 if
+// it isn't found.
+func placeholderLine(lines []string, name string) int {
+	for i, line := range lines {
+		if strings.Contains(line, "${"+name) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// readGooseGoMigration returns false for go files that don't register goose migrations.
+func readGooseGoMigration(fsys fs.FS, filename string, diags *builder.Diagnostics) ([]string, bool, error) {
+	src, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return nil, false, builder.FileError(builder.DiagnosticReadFailed, filename, err)
+	}
+	m, err := builder.ParseGooseGoMigration(filename, src)
+	if err != nil {
+		return nil, false, builder.FileError(builder.DiagnosticInvalidMigration, filename, err)
+	}
+	for _, warning := range m.Warnings {
+		*diags = append(*diags, builder.Diagnostic{
+			Severity: builder.SeverityWarning,
+			Code:     builder.DiagnosticGooseGoLogic,
+			File:     filename,
+			Line:     warning.Position.Line,
+			Column:   warning.Position.Column,
+			Message:  "goose go migration warning: " + warning.Message,
+		})
+	}
+	return m.Lines(), m.Registered, nil
+}
+
+// Manifest records the produced files and how each migration was converted.
+func (r Result) Manifest() builder.Manifest {
+	m := builder.NewManifest()
+	for _, f := range r.Files {
+		m.Files[f.Name] = builder.ContentHash(f.Content)
+	}
+	for _, migration := range r.Migrations {
+		m.Migrations = append(m.Migrations, migration.manifest())
+	}
+	return m
+}
+
+func (m Migration) manifest() builder.ManifestMigration {
+	entry := builder.ManifestMigration{
+		Source:          m.Source,
+		SourceDir:       m.SourceDir,
+		OriginalVersion: m.OriginalVersion,
+		Version:         m.Version,
+		Files:           m.Files,
+		UpTransaction:   m.UpTransaction,
+		DownTransaction: m.DownTransaction,
+		Warnings:        m.Warnings,
+	}
+	for _, squashed := range m.Squashed {
+		entry.Squashed = append(entry.Squashed, squashed.manifest())
+	}
+	return entry
+}
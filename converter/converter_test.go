@@ -0,0 +1,419 @@
+package converter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-companies.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE companies (id int);\n-- +migrate Down\nDROP TABLE companies;\n")},
+		"1-users.sql":     {Data: []byte("-- +migrate Up notransaction\nCREATE INDEX CONCURRENTLY users_id_idx ON users (id);\n")},
+		"README.md":       {Data: []byte("migrations")},
+	}
+	sink := converter.MemorySink{}
+
+	result, err := converter.Convert(context.Background(), converter.Options{
+		Source:      src,
+		Destination: sink,
+		DstType:     builder.DstTypeSqlMigrate,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []converter.Migration{
+		{
+			Source:          "1-companies.sql",
+			OriginalVersion: 1,
+			Version:         1,
+			Name:            "companies",
+			Format:          builder.SourceFormatSqlMigrate,
+			Files:           []string{"1_companies.up.sql", "1_companies.down.sql"},
+			UpTransaction:   true,
+			DownTransaction: true,
+		},
+		{
+			Source:          "1-users.sql",
+			OriginalVersion: 1,
+			Version:         2,
+			Name:            "users",
+			Format:          builder.SourceFormatSqlMigrate,
+			Files:           []string{"2_users.up.sql", "2_users.down.sql"},
+			Warnings:        []string{"version 1 of 1-users.sql is changed to 2"},
+		},
+	}, result.Migrations)
+	require.Equal(t, converter.MemorySink{
+		"1_companies.up.sql":   []byte("BEGIN;\n\nCREATE TABLE companies (id int);\nCOMMIT;\n\n"),
+		"1_companies.down.sql": []byte("BEGIN;\n\nDROP TABLE companies;\n\nCOMMIT;\n\n"),
+		"2_users.up.sql":       []byte("CREATE INDEX CONCURRENTLY users_id_idx ON users (id);\n"),
+		"2_users.down.sql":     []byte{},
+	}, sink)
+}
+
+func TestConvert_NothingWrittenOnError(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-companies.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE companies (id int);\n")},
+		"companies.sql":   {Data: []byte("-- +migrate Up\nCREATE TABLE users (id int);\n")},
+	}
+	sink := converter.MemorySink{}
+
+	_, err := converter.Convert(context.Background(), converter.Options{
+		Source:      src,
+		Destination: sink,
+	})
+	require.Error(t, err)
+	require.Empty(t, sink)
+}
+
+func TestConvert_ContinueOnError(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-companies.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE companies (id int);\n")},
+		"2_broken.go":     {Data: []byte("package")},
+		"companies.sql":   {Data: []byte("-- +migrate Up\nCREATE TABLE users (id int);\n")},
+	}
+
+	for _, writeValid := range []bool{false, true} {
+		sink := converter.MemorySink{}
+		result, err := converter.Convert(context.Background(), converter.Options{
+			Source:          src,
+			Destination:     sink,
+			ContinueOnError: true,
+			WriteValid:      writeValid,
+		})
+		require.ErrorIs(t, err, builder.ErrFilesFailed)
+		require.Len(t, result.Failures, 2)
+		require.Equal(t, "2_broken.go", result.Failures[0].File)
+		require.Equal(t, builder.DiagnosticInvalidFilename, result.Failures[1].Code)
+		require.Len(t, result.Migrations, 1)
+		if writeValid {
+			require.Len(t, sink, 2)
+		} else {
+			require.Empty(t, sink)
+		}
+	}
+}
+
+func TestConvert_Canceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := converter.Convert(ctx, converter.Options{
+		Source: fstest.MapFS{"1-companies.sql": {Data: []byte("SELECT 1;")}},
+	})
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestResult_Manifest(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-companies.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE companies (id int);\nSELECT '${MISSING}';\n")},
+		"1-users.sql":     {Data: []byte("-- +migrate Up notransaction\nCREATE INDEX CONCURRENTLY users_id_idx ON users (id);\n")},
+	}
+
+	result, err := converter.Convert(context.Background(), converter.Options{
+		Source:       src,
+		Placeholders: builder.Placeholders{Mode: builder.PlaceholderModeSubstitute, Lookup: func(string) (string, bool) { return "", false }},
+	})
+	require.NoError(t, err)
+	m := result.Manifest()
+	require.Len(t, m.Files, 4)
+	require.Equal(t, []builder.ManifestMigration{
+		{
+			Source:          "1-companies.sql",
+			OriginalVersion: 1,
+			Version:         1,
+			Files:           []string{"1_companies.up.sql", "1_companies.down.sql"},
+			UpTransaction:   true,
+			Warnings:        []string{"unresolved placeholder ${MISSING} in 1-companies.sql"},
+		},
+		{
+			Source:          "1-users.sql",
+			OriginalVersion: 1,
+			Version:         2,
+			Files:           []string{"2_users.up.sql", "2_users.down.sql"},
+			Warnings:        []string{"version 1 of 1-users.sql is changed to 2"},
+		},
+	}, m.Migrations)
+	require.Equal(t, []string{
+		"unresolved placeholder ${MISSING} in 1-companies.sql",
+		"version 1 of 1-users.sql is changed to 2",
+	}, result.Warnings)
+}
+
+func TestConvert_Diagnostics(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-companies.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE companies (id int);\nSELECT '${MISSING}';\n")},
+		"README.md":       {Data: []byte("# migrations\n")},
+	}
+
+	result, err := converter.Convert(context.Background(), converter.Options{Source: src})
+	require.NoError(t, err)
+	require.Equal(t, builder.Diagnostics{
+		{Severity: builder.SeverityInfo, Code: builder.DiagnosticSkipped, File: "README.md", Message: "not a migration file"},
+		{
+			Severity: builder.SeverityWarning,
+			Code:     builder.DiagnosticUnresolvedPlaceholder,
+			File:     "1-companies.sql",
+			Line:     3,
+			Message:  "unresolved placeholder ${MISSING} in 1-companies.sql",
+		},
+	}, result.Diagnostics)
+
+	src["2_broken.go"] = &fstest.MapFile{Data: []byte("package")}
+	result, err = converter.Convert(context.Background(), converter.Options{Source: src})
+	var d builder.Diagnostic
+	require.True(t, errors.As(err, &d))
+	require.Equal(t, builder.DiagnosticInvalidMigration, d.Code)
+	require.Equal(t, "2_broken.go", d.File)
+	require.Equal(t, d, result.Diagnostics[len(result.Diagnostics)-1])
+
+	src["2_seed.go"] = &fstest.MapFile{Data: []byte("package migrations\n\nfunc init() {\n\tgoose.AddMigration(upSeed, nil)\n}\n\n" +
+		"func upSeed(tx *sql.Tx) error {\n\tfor range names {\n\t}\n\treturn nil\n}\n")}
+	delete(src, "2_broken.go")
+	result, err = converter.Convert(context.Background(), converter.Options{Source: src})
+	require.NoError(t, err)
+	require.Contains(t, result.Diagnostics, builder.Diagnostic{
+		Severity: builder.SeverityWarning,
+		Code:     builder.DiagnosticGooseGoLogic,
+		File:     "2_seed.go",
+		Line:     8,
+		Column:   2,
+		Message:  "goose go migration warning: upSeed: loops can't be extracted statically",
+	})
+}
+
+func TestResult_Report(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-companies.sql": {Data: []byte("-- +goose Up\nCREATE TABLE companies (id int);\nCREATE INDEX companies_id_idx ON companies (id);\n")},
+		"notes.txt":       {Data: []byte("notes\n")},
+	}
+
+	result, err := converter.Convert(context.Background(), converter.Options{Source: src})
+	require.NoError(t, err)
+	report := result.Report(err)
+	require.Equal(t, []converter.ReportMigration{
+		{
+			Source:          "1-companies.sql",
+			Format:          builder.SourceFormatGoose,
+			OriginalVersion: 1,
+			Version:         1,
+			Name:            "companies",
+			Files: []converter.ReportFile{
+				{Name: "1_companies.up.sql", Direction: "up", Transaction: true, Statements: []int{1, 3, 4, 5}},
+				{Name: "1_companies.down.sql", Direction: "down", Statements: []int{}},
+			},
+		},
+	}, report.Migrations)
+	require.Equal(t, []converter.Skipped{{Name: "notes.txt", Reason: "not a migration file"}}, report.Skipped)
+	require.Empty(t, report.Error)
+
+	report = result.Report(context.Canceled)
+	require.Equal(t, context.Canceled.Error(), report.Error)
+}
+
+func TestConvert_Versions(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-indexes.sql":   {Data: []byte("-- +migrate Up\nSELECT 1;\n")},
+		"2-companies.sql": {Data: []byte("-- +migrate Up\nSELECT 2;\n")},
+	}
+
+	result, err := converter.Convert(context.Background(), converter.Options{
+		Source:   src,
+		Versions: map[string]int64{"1-indexes.sql": 20230102100000},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Migrations, 2)
+	require.Equal(t, int64(1), result.Migrations[0].OriginalVersion)
+	require.Equal(t, int64(20230102100000), result.Migrations[0].Version)
+	require.Equal(t, int64(20230102100001), result.Migrations[1].Version)
+}
+
+func TestConvert_VersionOrder(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-a.sql":  {Data: []byte("-- +migrate Up\nSELECT 1;\n")},
+		"10-c.sql": {Data: []byte("-- +migrate Up\nSELECT 10;\n")},
+		"2-b.sql":  {Data: []byte("-- +migrate Up\nSELECT 2;\n")},
+	}
+
+	for _, versioning := range []builder.Versioning{builder.VersioningBump, builder.VersioningPreserve} {
+		result, err := converter.Convert(context.Background(), converter.Options{
+			Source:     src,
+			Versioning: versioning,
+		})
+		require.NoError(t, err)
+		versions := make([]int64, 0, len(result.Migrations))
+		for _, m := range result.Migrations {
+			versions = append(versions, m.Version)
+		}
+		require.Equal(t, []int64{1, 2, 10}, versions, versioning)
+	}
+}
+
+func TestConvert_Sources(t *testing.T) {
+	t.Parallel()
+	billing := fstest.MapFS{
+		"00002_invoices.sql": {Data: []byte("-- +goose Up\nCREATE TABLE invoices (id int);\n")},
+	}
+	users := fstest.MapFS{
+		"1-users.sql":  {Data: []byte("-- +migrate Up\nCREATE TABLE users (id int);\n")},
+		"2-emails.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE emails (id int);\n")},
+	}
+
+	result, err := converter.Convert(context.Background(), converter.Options{
+		Sources: []converter.SourceDir{
+			{Name: "billing", FS: billing, Prefix: "billing"},
+			{Name: "users", FS: users},
+		},
+	})
+	require.NoError(t, err)
+	names := make([]string, 0)
+	for _, m := range result.Migrations {
+		names = append(names, m.SourceDir+":"+m.Files[0])
+	}
+	require.Equal(t, []string{
+		"users:1_users.up.sql",
+		"billing:2_billing_invoices.up.sql",
+		"users:3_emails.up.sql",
+	}, names)
+
+	_, err = converter.Convert(context.Background(), converter.Options{
+		Sources: []converter.SourceDir{
+			{Name: "billing", FS: billing},
+			{Name: "users", FS: users},
+		},
+		Versioning: builder.VersioningPreserve,
+	})
+	require.ErrorIs(t, err, builder.ErrVersionCollision)
+	require.ErrorContains(t, err, "billing/00002_invoices.sql and users/2-emails.sql")
+
+	_, err = converter.Convert(context.Background(), converter.Options{
+		Sources: []converter.SourceDir{
+			{Name: "users", FS: billing},
+			{Name: "users", FS: users},
+		},
+	})
+	require.ErrorIs(t, err, builder.ErrDuplicateSourceName)
+}
+
+func TestConvert_Discovery(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-users.sql":            {Data: []byte("-- +migrate Up\nCREATE TABLE users (id int);\n")},
+		"schema.sql":             {Data: []byte("CREATE TABLE users (id int);\n")},
+		"README.md":              {Data: []byte("migrations")},
+		"billing/2-invoices.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE invoices (id int);\n")},
+		"billing/0-legacy.sql":   {Data: []byte("-- +migrate Up\nCREATE TABLE legacy (id int);\n")},
+		"seeds/3-users.sql":      {Data: []byte("INSERT INTO users VALUES (1);\n")},
+	}
+
+	result, err := converter.Convert(context.Background(), converter.Options{Source: src})
+	require.NoError(t, err)
+	require.Len(t, result.Migrations, 1)
+	require.Equal(t, []converter.Skipped{
+		{Name: "README.md", Reason: "not a migration file"},
+		{Name: "billing", Reason: "nested folders are walked only if recursive"},
+		{Name: "schema.sql", Reason: `excluded by "schema.sql"`},
+		{Name: "seeds", Reason: "nested folders are walked only if recursive"},
+	}, result.Skipped)
+
+	result, err = converter.Convert(context.Background(), converter.Options{
+		Source:    src,
+		Recursive: true,
+		Include:   []string{"*.sql"},
+		Exclude:   []string{"seeds", "billing/0-*"},
+	})
+	require.NoError(t, err)
+	sources := make([]string, 0)
+	for _, m := range result.Migrations {
+		sources = append(sources, m.Source)
+	}
+	require.Equal(t, []string{"1-users.sql", "billing/2-invoices.sql"}, sources)
+	require.Equal(t, []converter.Skipped{
+		{Name: "README.md", Reason: "not matched by the include patterns"},
+		{Name: "billing/0-legacy.sql", Reason: `excluded by "billing/0-*"`},
+		{Name: "schema.sql", Reason: `excluded by "schema.sql"`},
+		{Name: "seeds", Reason: `excluded by "seeds"`},
+	}, result.Skipped)
+
+	_, err = converter.Convert(context.Background(), converter.Options{Source: src, Include: []string{"["}})
+	require.Error(t, err)
+}
+
+func TestConvert_Squash(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-users.sql":  {Data: []byte("-- +migrate Up\nCREATE TABLE users (id int);\n-- +migrate Down\nDROP TABLE users;\n")},
+		"2-emails.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE emails (id int);\n-- +migrate Down\nDROP TABLE emails;\n")},
+		"3-index.sql":  {Data: []byte("-- +migrate Up notransaction\nCREATE INDEX CONCURRENTLY emails_idx ON emails (id);\n")},
+		"4-phones.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE phones (id int);\n")},
+	}
+
+	result, err := converter.Convert(context.Background(), converter.Options{
+		Source: src,
+		Squash: &converter.SquashRange{From: 1, To: 3},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Migrations, 3)
+	require.Equal(t, []string{"2_squashed_1.up.sql", "2_squashed_1.down.sql"}, result.Migrations[0].Files)
+	require.Equal(t, "1-users.sql", result.Migrations[0].Squashed[0].Source)
+	require.Equal(t, "2-emails.sql", result.Migrations[0].Squashed[1].Source)
+	require.Equal(t, []string{"3_squashed_2.up.sql", "3_squashed_2.down.sql"}, result.Migrations[1].Files)
+	require.False(t, result.Migrations[1].UpTransaction)
+	require.Equal(t, []string{"4_phones.up.sql", "4_phones.down.sql"}, result.Migrations[2].Files)
+	require.Equal(t, "BEGIN;\n\n-- 2-emails.sql\nDROP TABLE emails;\n-- 1-users.sql\nDROP TABLE users;\n\nCOMMIT;\n\n",
+		string(result.Files[1].Content))
+
+	manifest := result.Manifest()
+	require.Len(t, manifest.Migrations[0].Squashed, 2)
+	require.Equal(t, int64(1), manifest.Migrations[0].Squashed[0].Version)
+	require.Equal(t, []builder.StampVersion{
+		{SourceID: "1-users.sql", Version: 2},
+		{SourceID: "2-emails.sql", Version: 2},
+		{SourceID: "3-index.sql", Version: 3},
+		{SourceID: "4-phones.sql", Version: 4},
+	}, converter.StampVersions(builder.HistorySourceSqlMigrate, manifest.Migrations))
+
+	_, err = converter.Convert(context.Background(), converter.Options{
+		Source: src,
+		Squash: &converter.SquashRange{From: 10, To: 20},
+	})
+	require.ErrorIs(t, err, builder.ErrEmptySquashRange)
+}
+
+func TestConvertMigration(t *testing.T) {
+	t.Parallel()
+	content := []byte("-- +goose Up\nCREATE TABLE companies (id int);\n-- +goose Down\nDROP TABLE companies;\n")
+
+	result, err := converter.ConvertMigration(context.Background(), "stdin/20240101_companies.sql", content, converter.Options{
+		DstType: builder.DstTypeSqlMigrate,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []converter.Migration{
+		{
+			Source:          "20240101_companies.sql",
+			OriginalVersion: 20240101,
+			Version:         20240101,
+			Name:            "companies",
+			Format:          builder.SourceFormatGoose,
+			Files:           []string{"20240101_companies.up.sql", "20240101_companies.down.sql"},
+			UpTransaction:   true,
+			DownTransaction: true,
+		},
+	}, result.Migrations)
+	require.Equal(t, []builder.File{
+		{Name: "20240101_companies.up.sql", Content: []byte("BEGIN;\n\nCREATE TABLE companies (id int);\nCOMMIT;\n\n")},
+		{Name: "20240101_companies.down.sql", Content: []byte("BEGIN;\n\nDROP TABLE companies;\n\nCOMMIT;\n\n")},
+	}, result.Files)
+}
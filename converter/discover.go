@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultExcludes are the known non-migration files of the sources.
+var DefaultExcludes = []string{"schema.sql", "structure.sql"}
+
+// Skipped is a source file that is not converted.
+type Skipped struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+func (s Skipped) String() string {
+	return fmt.Sprintf("skip %s: %s", s.Name, s.Reason)
+}
+
+// folder is a source folder with the names of its files left to load.
+type folder struct {
+	dir       string
+	filenames []string
+}
+
+func (f folder) path(filename string) string {
+	return path.Join(f.dir, filename)
+}
+
+// discover returns the folders of fsys with the files matching the patterns,
+// the top one only unless opts.Recursive is set.
+func discover(fsys fs.FS, opts Options, skipped *[]Skipped) ([]folder, error) {
+	excludes := append(append([]string(nil), DefaultExcludes...), opts.Exclude...)
+	for _, pattern := range append(excludes, opts.Include...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "pattern %q", pattern)
+		}
+	}
+
+	folders := make([]folder, 0)
+	indexes := make(map[string]int)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if pattern, ok := matchPattern(excludes, name); ok {
+			*skipped = append(*skipped, Skipped{Name: name, Reason: fmt.Sprintf("excluded by %q", pattern)})
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if !opts.Recursive {
+				*skipped = append(*skipped, Skipped{Name: name, Reason: "nested folders are walked only if recursive"})
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if _, ok := matchPattern(opts.Include, name); len(opts.Include) != 0 && !ok {
+			*skipped = append(*skipped, Skipped{Name: name, Reason: "not matched by the include patterns"})
+			return nil
+		}
+		dir, filename := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+		i, ok := indexes[dir]
+		if !ok {
+			i = len(folders)
+			indexes[dir] = i
+			folders = append(folders, folder{dir: dir})
+		}
+		folders[i].filenames = append(folders[i].filenames, filename)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "read src migrations folder")
+	}
+	return folders, nil
+}
+
+// matchPattern returns the first pattern matching the name.
+func matchPattern(patterns []string, name string) (string, bool) {
+	for _, pattern := range patterns {
+		target := path.Base(name)
+		if strings.Contains(pattern, "/") {
+			target = name
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
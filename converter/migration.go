@@ -0,0 +1,89 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// ConvertMigration converts a single source migration given with its
+// filename, e.g. one read from stdin, without reading any folder. The
+// filename gives the version and the name of the destination files.
+func ConvertMigration(ctx context.Context, filename string, content []byte, opts Options) (Result, error) {
+	opts.Source = fileFS{name: path.Base(filename), content: content}
+	opts.Sources = nil
+	opts.Versions = nil
+	opts.Recursive = false
+	opts.Include = nil
+	opts.Exclude = nil
+	return Convert(ctx, opts)
+}
+
+// fileFS is a folder with a single file.
+type fileFS struct {
+	name    string
+	content []byte
+}
+
+func (f fileFS) Open(name string) (fs.File, error) {
+	if name != f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memFile{Reader: bytes.NewReader(f.content), info: f.fileInfo()}, nil
+}
+
+func (f fileFS) Stat(name string) (fs.FileInfo, error) {
+	switch name {
+	case ".":
+		return fileInfo{name: ".", mode: fs.ModeDir | 0o755}, nil
+	case f.name:
+		return f.fileInfo(), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (f fileFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return []fs.DirEntry{fs.FileInfoToDirEntry(f.fileInfo())}, nil
+}
+
+func (f fileFS) ReadFile(name string) ([]byte, error) {
+	if name != f.name {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), f.content...), nil
+}
+
+func (f fileFS) fileInfo() fileInfo {
+	return fileInfo{name: f.name, size: int64(len(f.content)), mode: 0o644}
+}
+
+type memFile struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i fileInfo) Sys() any           { return nil }
@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+// Report is a machine-readable record of a conversion run.
+type Report struct {
+	Migrations  []ReportMigration   `json:"migrations"`
+	Skipped     []Skipped           `json:"skipped"`
+	Diagnostics builder.Diagnostics `json:"diagnostics"`
+	// Error is the error the run stopped with.
+	Error string `json:"error,omitempty"`
+}
+
+type ReportMigration struct {
+	Source          string               `json:"source,omitempty"`
+	SourceDir       string               `json:"source_dir,omitempty"`
+	Format          builder.SourceFormat `json:"format,omitempty"`
+	OriginalVersion int64                `json:"original_version,omitempty"`
+	Version         int64                `json:"version"`
+	Name            string               `json:"name"`
+	Files           []ReportFile         `json:"files,omitempty"`
+	Warnings        []string             `json:"warnings,omitempty"`
+	Squashed        []ReportMigration    `json:"squashed,omitempty"`
+}
+
+// ReportFile is a produced file with the points the sql is split at.
+type ReportFile struct {
+	Name        string `json:"name"`
+	Direction   string `json:"direction"`
+	Transaction bool   `json:"transaction"`
+	// Statements are the lines where the statements of the file start.
+	Statements []int `json:"statements"`
+}
+
+// Report records the run, err is the error Convert returned.
+func (r Result) Report(err error) Report {
+	report := Report{
+		Migrations:  make([]ReportMigration, 0, len(r.Migrations)),
+		Skipped:     r.Skipped,
+		Diagnostics: r.Diagnostics,
+	}
+	if report.Skipped == nil {
+		report.Skipped = make([]Skipped, 0)
+	}
+	if report.Diagnostics == nil {
+		report.Diagnostics = make(builder.Diagnostics, 0)
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	contents := make(map[string][]byte, len(r.Files))
+	for _, f := range r.Files {
+		contents[f.Name] = f.Content
+	}
+	for _, m := range r.Migrations {
+		report.Migrations = append(report.Migrations, m.report(contents))
+	}
+	return report
+}
+
+func (m Migration) report(contents map[string][]byte) ReportMigration {
+	entry := ReportMigration{
+		Source:          m.Source,
+		SourceDir:       m.SourceDir,
+		Format:          m.Format,
+		OriginalVersion: m.OriginalVersion,
+		Version:         m.Version,
+		Name:            m.Name,
+		Warnings:        m.Warnings,
+	}
+	for _, name := range m.Files {
+		f := ReportFile{Name: name, Direction: "down", Transaction: m.DownTransaction}
+		if strings.HasSuffix(name, ".up.sql") {
+			f.Direction, f.Transaction = "up", m.UpTransaction
+		}
+		f.Statements = builder.StatementLines(strings.Split(string(contents[name]), "\n"))
+		entry.Files = append(entry.Files, f)
+	}
+	for _, squashed := range m.Squashed {
+		entry.Squashed = append(entry.Squashed, squashed.report(contents))
+	}
+	return entry
+}
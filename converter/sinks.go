@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+// Sink receives the converted files.
+type Sink interface {
+	WriteFile(name string, content []byte) error
+}
+
+// DirSink writes the files to an OS directory, creating it if needed.
+// If Clean is set, the directory contents are removed before the first write.
+type DirSink struct {
+	Dir   string
+	Clean bool
+
+	prepared bool
+}
+
+func (s *DirSink) WriteFile(name string, content []byte) error {
+	if !s.prepared {
+		if err := os.MkdirAll(s.Dir, os.ModePerm); err != nil {
+			return errors.Wrap(err, "create dest dir")
+		}
+		if s.Clean {
+			if err := builder.RemoveContents(s.Dir); err != nil {
+				return errors.Wrap(err, "clear dest migrations folder")
+			}
+		}
+		s.prepared = true
+	}
+	return os.WriteFile(filepath.Join(s.Dir, name), content, 0o644)
+}
+
+// MemorySink keeps the files in memory by their names.
+type MemorySink map[string][]byte
+
+func (s MemorySink) WriteFile(name string, content []byte) error {
+	s[name] = append(make([]byte, 0, len(content)), content...)
+	return nil
+}
+
+// TarSink writes the files to a tar archive, Close has to be called
+// to flush it. The files modification time is fixed, so the archive is
+// the same for the same conversion.
+type TarSink struct {
+	tw *tar.Writer
+}
+
+func NewTarSink(w io.Writer) *TarSink {
+	return &TarSink{tw: tar.NewWriter(w)}
+}
+
+func (s *TarSink) WriteFile(name string, content []byte) error {
+	if err := s.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(content)),
+		ModTime:  time.Unix(0, 0),
+	}); err != nil {
+		return err
+	}
+	_, err := s.tw.Write(content)
+	return err
+}
+
+func (s *TarSink) Close() error {
+	return s.tw.Close()
+}
+
+// ZipSink writes the files to a zip archive, Close has to be called to flush it.
+type ZipSink struct {
+	zw *zip.Writer
+}
+
+func NewZipSink(w io.Writer) *ZipSink {
+	return &ZipSink{zw: zip.NewWriter(w)}
+}
+
+func (s *ZipSink) WriteFile(name string, content []byte) error {
+	w, err := s.zw.CreateHeader(&zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+func (s *ZipSink) Close() error {
+	return s.zw.Close()
+}
@@ -0,0 +1,85 @@
+package converter_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/converter"
+)
+
+var sinkFiles = []struct {
+	name    string
+	content string
+}{
+	{"1_companies.up.sql", "CREATE TABLE companies (id int);\n"},
+	{"1_companies.down.sql", "DROP TABLE companies;\n"},
+}
+
+func TestTarSink(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	sink := converter.NewTarSink(&buf)
+	for _, f := range sinkFiles {
+		require.NoError(t, sink.WriteFile(f.name, []byte(f.content)))
+	}
+	require.NoError(t, sink.Close())
+
+	tr := tar.NewReader(&buf)
+	for _, f := range sinkFiles {
+		hdr, err := tr.Next()
+		require.NoError(t, err)
+		require.Equal(t, f.name, hdr.Name)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		require.Equal(t, f.content, string(content))
+	}
+	_, err := tr.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestZipSink(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	sink := converter.NewZipSink(&buf)
+	for _, f := range sinkFiles {
+		require.NoError(t, sink.WriteFile(f.name, []byte(f.content)))
+	}
+	require.NoError(t, sink.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, zr.File, len(sinkFiles))
+	for i, f := range sinkFiles {
+		rc, err := zr.File[i].Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		require.Equal(t, f.name, zr.File[i].Name)
+		require.Equal(t, f.content, string(content))
+	}
+}
+
+func TestDirSink(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stale.up.sql"), []byte("SELECT 1;"), 0o644))
+
+	sink := &converter.DirSink{Dir: dir, Clean: true}
+	for _, f := range sinkFiles {
+		require.NoError(t, sink.WriteFile(f.name, []byte(f.content)))
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, len(sinkFiles))
+	content, err := os.ReadFile(filepath.Join(dir, sinkFiles[0].name))
+	require.NoError(t, err)
+	require.Equal(t, sinkFiles[0].content, string(content))
+}
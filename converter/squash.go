@@ -0,0 +1,72 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+// DefaultSquashName is the name of the squashed migration.
+const DefaultSquashName = "squashed"
+
+// SquashRange folds the migrations with destination versions from From to To.
+type SquashRange struct {
+	From int64
+	To   int64
+	// Name of the squashed migration, DefaultSquashName if empty.
+	Name string
+}
+
+// squash replaces the migrations of the range with the squashed parts.
+// The parts take the last versions of the range, so a database migrated
+// to the end of the range stays at the same version.
+func squash(r SquashRange, converted []Migration, data []builder.MigrationData) ([]Migration, []builder.MigrationData, error) {
+	first, last := -1, -1
+	for i, m := range converted {
+		if m.Version >= r.From && m.Version <= r.To {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first == -1 {
+		return nil, nil, fmt.Errorf("%w: %d-%d", builder.ErrEmptySquashRange, r.From, r.To)
+	}
+
+	folded := make([]builder.Migration, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		folded = append(folded, builder.Migration{
+			Filename: sourceName(builder.Migration{Dir: converted[i].SourceDir, Filename: converted[i].Source}),
+			Data:     data[i],
+		})
+	}
+	parts := builder.Squash(folded)
+
+	name := r.Name
+	if name == "" {
+		name = DefaultSquashName
+	}
+	squashedMigrations := append([]Migration(nil), converted[:first]...)
+	squashedData := append([]builder.MigrationData(nil), data[:first]...)
+	for i, part := range parts {
+		m := Migration{
+			Version:         converted[last-len(parts)+1+i].Version,
+			Name:            name,
+			UpTransaction:   part.Data.Up.Transaction,
+			DownTransaction: part.Data.Down.Transaction,
+		}
+		if len(parts) > 1 {
+			m.Name = fmt.Sprintf("%s_%d", name, i+1)
+		}
+		for _, index := range part.Parts {
+			m.Squashed = append(m.Squashed, converted[first+index])
+			m.Warnings = append(m.Warnings, converted[first+index].Warnings...)
+		}
+		squashedMigrations = append(squashedMigrations, m)
+		squashedData = append(squashedData, part.Data)
+	}
+	squashedMigrations = append(squashedMigrations, converted[last+1:]...)
+	squashedData = append(squashedData, data[last+1:]...)
+	return squashedMigrations, squashedData, nil
+}
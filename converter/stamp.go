@@ -0,0 +1,40 @@
+package converter
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+// StampVersions maps the ids the converted migrations are recorded with
+// in the source history table to their converted versions. The migrations
+// are the ones of Result.Manifest or of the manifest of the destination,
+// the ones folded into a squashed migration are mapped to its version.
+func StampVersions(source builder.HistorySource, migrations []builder.ManifestMigration) []builder.StampVersion {
+	versions := make([]builder.StampVersion, 0, len(migrations))
+	for _, m := range migrations {
+		if len(m.Squashed) != 0 {
+			for _, squashed := range StampVersions(source, m.Squashed) {
+				squashed.Version = m.Version
+				versions = append(versions, squashed)
+			}
+			continue
+		}
+		var sourceID string
+		switch source {
+		case builder.HistorySourceSqlMigrate:
+			// gorp_migrations keeps the file names
+			sourceID = path.Base(m.Source)
+		case builder.HistorySourceDbmate:
+			// schema_migrations keeps the version as it's written in the file name
+			name := path.Base(m.Source)
+			sourceID = name[:len(name)-len(strings.TrimLeft(name, "0123456789"))]
+		default:
+			sourceID = strconv.FormatInt(m.OriginalVersion, 10)
+		}
+		versions = append(versions, builder.StampVersion{SourceID: sourceID, Version: m.Version})
+	}
+	return versions
+}
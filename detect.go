@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
+)
+
+// formatMixed is the -summary of a folder with migrations of several formats.
+const formatMixed = "mixed"
+
+func runDetect(args []string) {
+	var (
+		srcMigrPath string
+		recursive   bool
+		include     patterns
+		exclude     patterns
+		summary     bool
+	)
+	fs := newFlagSet("detect", "[options]",
+		"Prints the source format and the path of each migration separated by a tab: goose, goose-go,\n"+
+			"sql-migrate, dbmate, go-pg or plain for sql files without annotations.")
+	fs.StringVar(&srcMigrPath, "src", "src", "source migrations folder")
+	fs.BoolVar(&recursive, "recursive", false, "walk the nested folders of the source")
+	fs.Var(&include, "include", "glob of the files to detect; can be repeated")
+	fs.Var(&exclude, "exclude", "glob of the files to skip; can be repeated")
+	fs.BoolVar(&summary, "summary", false, "print only the format of the folder, "+formatMixed+" if the migrations use several")
+	registerDiagnostics(fs)
+	registerConfig(fs)
+	parseFlags(fs, args)
+
+	if srcMigrPath == "" {
+		usagef("validate error: %s", builder.ErrNoSrcFolderPath.Error())
+	}
+	if _, err := os.Stat(srcMigrPath); os.IsNotExist(err) {
+		usagef("source migration directory %s doesn't exists", srcMigrPath)
+	}
+
+	sourceRoot = srcMigrPath
+	migrations, diags, err := converter.LoadMigrations(context.Background(), converter.Options{
+		Source:    os.DirFS(srcMigrPath),
+		Recursive: recursive,
+		Include:   include,
+		Exclude:   exclude,
+	})
+	reportSource(diags.Failures()...)
+	if err != nil {
+		fatalSource(err, "load migrations error: %s", err.Error())
+	}
+	if len(migrations) == 0 {
+		fatalf("no migrations found in %s", srcMigrPath)
+	}
+
+	if summary {
+		format := string(migrations[0].Format)
+		for _, m := range migrations[1:] {
+			if string(m.Format) != format {
+				format = formatMixed
+				break
+			}
+		}
+		fmt.Println(format)
+		return
+	}
+	for _, m := range migrations {
+		fmt.Printf("%s\t%s\n", m.Format, filepath.Join(srcMigrPath, m.Filename))
+	}
+}
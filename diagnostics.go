@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+var (
+	// reporter prints the diagnostics of every command in the -diagnostics format.
+	reporter          = builder.NewReporter(builder.ReportFormatText, os.Stderr)
+	diagnosticsFormat = builder.ReportFormatText
+)
+
+// registerDiagnostics adds the -diagnostics flag to the command flags.
+func registerDiagnostics(fs *flag.FlagSet) {
+	fs.Func("diagnostics", "diagnostics format: text (default), json or github; github workflow annotations are printed to stdout", func(value string) error {
+		format, err := builder.GetReportFormat(value)
+		if err != nil {
+			return err
+		}
+		var w io.Writer = os.Stderr
+		if format == builder.ReportFormatGitHub {
+			w = os.Stdout
+		}
+		reporter, diagnosticsFormat = builder.NewReporter(format, w), format
+		return nil
+	})
+}
+
+// sourceRoot is the single source folder, the files of its diagnostics are
+// relative to it.
+var sourceRoot string
+
+// reportSource reports the diagnostics of the source files with their paths
+// from the working directory, so annotations point to the right files.
+func reportSource(diags ...builder.Diagnostic) {
+	for _, d := range diags {
+		reporter.Report(sourceDiagnostic(d))
+	}
+}
+
+func sourceDiagnostic(d builder.Diagnostic) builder.Diagnostic {
+	if d.File != "" {
+		d.File = filepath.Join(sourceRoot, d.File)
+	}
+	return d
+}
+
+func report(diags ...builder.Diagnostic) {
+	for _, d := range diags {
+		reporter.Report(d)
+	}
+}
+
+func warnf(file, format string, args ...any) {
+	report(builder.Diagnostic{Severity: builder.SeverityWarning, File: file, Message: fmt.Sprintf(format, args...)})
+}
+
+func errorDiagnostic(format string, args ...any) builder.Diagnostic {
+	return builder.Diagnostic{Severity: builder.SeverityError, Message: fmt.Sprintf(format, args...)}
+}
+
+// fatalf reports the error and exits with exitFailed.
+func fatalf(format string, args ...any) {
+	report(errorDiagnostic(format, args...))
+	exit(exitFailed)
+}
+
+// fatalSource reports the error and exits with exitFailed, errors raised for a
+// source file are reported with the file.
+func fatalSource(err error, format string, args ...any) {
+	var d builder.Diagnostic
+	if errors.As(err, &d) {
+		reportSource(d)
+		exit(exitFailed)
+	}
+	fatalf(format, args...)
+}
+
+// failures are the files that failed with -continue-on-error, summarized
+// when the command finishes.
+var failures builder.Diagnostics
+
+// closeReporter flushes the reported diagnostics and prints the failures.
+func closeReporter() {
+	if err := reporter.Close(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "report diagnostics error: %s\n", err.Error())
+	}
+	printFailures()
+}
+
+// finish flushes the diagnostics when a command returns, exiting with
+// exitFailed if some files failed.
+func finish() {
+	if len(failures) != 0 {
+		exit(exitFailed)
+	}
+	closeReporter()
+}
+
+// printFailures prints the summary of the failures in the text format, the
+// other formats have them among the diagnostics.
+func printFailures() {
+	if len(failures) == 0 || diagnosticsFormat != builder.ReportFormatText {
+		failures = nil
+		return
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "\n%d files failed:\n", len(failures))
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FILE\tCODE\tERROR")
+	for _, d := range failures {
+		file, code := d.File, string(d.Code)
+		if file == "" {
+			file = "-"
+		}
+		if code == "" {
+			code = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", file, code, d.Message)
+	}
+	_ = w.Flush()
+	failures = nil
+}
+
+func exit(code int) {
+	closeReporter()
+	os.Exit(code)
+}
@@ -3,12 +3,15 @@ module github.com/musinit/migradaptor
 go 1.20
 
 require (
+	github.com/golang-migrate/migrate/v4 v4.16.2
 	github.com/pkg/errors v0.9.1
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/rubenv/sql-migrate v1.5.2
 	github.com/stretchr/testify v1.8.4
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/go-gorp/gorp/v3 v3.1.0 // indirect
 )
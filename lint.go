@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
+)
+
+// ruleSeverities is a repeatable rule=severity flag.
+type ruleSeverities map[builder.LintRule]builder.Severity
+
+func (r ruleSeverities) String() string {
+	pairs := make([]string, 0, len(r))
+	for rule, severity := range r {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", rule, severity))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (r ruleSeverities) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		rule, severity, err := builder.ParseLintRuleSeverity(pair)
+		if err != nil {
+			return err
+		}
+		r[rule] = severity
+	}
+	return nil
+}
+
+func runLint(args []string) {
+	var (
+		srcMigrPath string
+		phMode      string
+		varsPath    string
+		failOn      string
+		severities  = ruleSeverities(builder.DefaultLintSeverities())
+	)
+	fs := newFlagSet("lint", "[options]",
+		lintDescription())
+	fs.StringVar(&srcMigrPath, "src", "src", "source migrations folder")
+	fs.StringVar(&phMode, "placeholders", "keep", "${VAR} placeholders handling: keep, substitute or rewrite")
+	fs.StringVar(&varsPath, "vars-file", "", "KEY=VALUE file with placeholder values, environment is used if not set")
+	fs.StringVar(&failOn, "fail-on", string(builder.SeverityError), "exit with non-zero code if there are issues of this severity or higher")
+	fs.Var(severities, "rule", "rule=severity, severity is one of off, info, warning, error; can be repeated")
+	registerDiagnostics(fs)
+	registerConfig(fs)
+	parseFlags(fs, args)
+
+	failOnSeverity, err := builder.GetSeverity(failOn)
+	if err != nil {
+		usagef("get fail-on severity error: %s", err.Error())
+	}
+	if srcMigrPath == "" {
+		usagef("validate error: %s", builder.ErrNoSrcFolderPath.Error())
+	}
+
+	migrations, diags, err := converter.LoadMigrations(context.Background(), converter.Options{
+		Source:       os.DirFS(srcMigrPath),
+		Placeholders: newPlaceholders(phMode, varsPath),
+	})
+	sourceRoot = srcMigrPath
+	reportSource(diags...)
+	if err != nil {
+		fatalSource(err, "load migrations error: %s", err.Error())
+	}
+	issues := builder.Lint(migrations, severities)
+	for _, issue := range issues {
+		reportSource(issue.Diagnostic())
+	}
+	if builder.HasLintIssues(issues, failOnSeverity) {
+		exit(exitFailed)
+	}
+}
+
+// lintDescription lists the rules with their default severities.
+func lintDescription() string {
+	defaults := builder.DefaultLintSeverities()
+	rules := make([]string, 0, len(defaults))
+	for rule, severity := range defaults {
+		rules = append(rules, fmt.Sprintf("  %s (default %s)", rule, severity))
+	}
+	sort.Strings(rules)
+	return "Runs the rules over the source migrations parsed the same way as for the conversion.\n\nRules:\n" +
+		strings.Join(rules, "\n")
+}
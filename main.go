@@ -1,154 +1,461 @@
 package main
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime/debug"
+	"strings"
 
 	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
 )
 
-func main() {
-	var (
-		dstType     string
-		srcMigrPath string
-		dstMigrPath string
-		flgVersion  bool
-		helpPtr     bool
-	)
-	flag.BoolVar(&flgVersion, "version", false, "if true, print version and exit")
-	flag.BoolVar(&helpPtr, "help", false, "print help information")
-	flag.StringVar(&dstType, "dst-lib", "golang-migrate", "destination library format")
-	flag.StringVar(&srcMigrPath, "src", "src", "source migrations folder")
-	flag.StringVar(&dstMigrPath, "dst", "dst", "destination migrations folder")
-	flag.Parse()
+// sourcePaths is a repeatable [prefix=]path flag, path can be a glob.
+type sourcePaths []string
 
-	switch {
-	case flgVersion:
-		println(GetVersion())
-		os.Exit(0)
-	case helpPtr:
-		PrintHelp()
-		os.Exit(0)
+func (s *sourcePaths) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *sourcePaths) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// patterns is a repeatable flag of comma-separated patterns.
+type patterns []string
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(value string) error {
+	*p = append(*p, strings.Split(value, ",")...)
+	return nil
+}
+
+// sourcePath is a source folder with the prefix of its migration names.
+type sourcePath struct {
+	prefix string
+	path   string
+}
+
+// expand returns the source folders matching the flags, "src" if none is given.
+func (s sourcePaths) expand() ([]sourcePath, error) {
+	if len(s) == 0 {
+		return []sourcePath{{path: "src"}}, nil
 	}
+	paths := make([]sourcePath, 0, len(s))
+	for _, value := range s {
+		prefix, pattern, ok := strings.Cut(value, "=")
+		if !ok {
+			prefix, pattern = "", value
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			// reported as a missing folder
+			matches = []string{pattern}
+		}
+		for _, match := range matches {
+			paths = append(paths, sourcePath{prefix: prefix, path: match})
+		}
+	}
+	return paths, nil
+}
+
+// convertOptions are the flags shared by the commands that run the conversion.
+type convertOptions struct {
+	dstType      string
+	srcMigrPaths sourcePaths
+	dstMigrPath  string
+	phMode       string
+	varsPath     string
+	genDown      bool
+	versioning   string
+	gitVersions  bool
+	recursive    bool
+	include      patterns
+	exclude      patterns
+
+	continueOnError bool
+	writeValid      bool
+}
 
-	if err := builder.ValidateInput(&dstType, &srcMigrPath, &dstMigrPath); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "validate error: %s\n Run migrator -help for information.\n", err.Error())
-		os.Exit(1)
+func (o *convertOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.dstType, "dst-lib", "golang-migrate", "destination library format")
+	fs.Var(&o.srcMigrPaths, "src", "source migrations folder, [prefix=]path or a glob; can be repeated (default src)")
+	fs.StringVar(&o.dstMigrPath, "dst", "dst", "destination migrations folder, or a .tar, .tar.gz, .tgz or .zip archive")
+	fs.StringVar(&o.phMode, "placeholders", "keep", "${VAR} placeholders handling: keep, substitute or rewrite")
+	fs.StringVar(&o.varsPath, "vars-file", "", "KEY=VALUE file with placeholder values, environment is used if not set")
+	fs.BoolVar(&o.genDown, "gen-down", false, "generate empty down sections from the up ones")
+	fs.BoolVar(&o.gitVersions, "git-versions", false, "take source versions from the time the files were first committed to git")
+	fs.StringVar(&o.versioning, "versioning", string(builder.VersioningBump), "destination versions: bump, preserve, sequential, timestamp or hybrid")
+	fs.BoolVar(&o.recursive, "recursive", false, "walk the nested folders of the sources")
+	fs.Var(&o.include, "include", "glob of the files to convert, matched against the name or the path if it has a slash; can be repeated")
+	fs.Var(&o.exclude, "exclude", "glob of the files to skip in addition to "+strings.Join(converter.DefaultExcludes, ", ")+"; can be repeated")
+	fs.BoolVar(&o.continueOnError, "continue-on-error", false, "convert every file and summarize the failures instead of stopping at the first one")
+	fs.BoolVar(&o.writeValid, "write-valid", false, "with -continue-on-error, write the files converted even if some failed")
+	registerDiagnostics(fs)
+	registerReport(fs)
+	registerConfig(fs)
+}
+
+// options validates the flags and returns the absolute destination path
+// and the conversion options without a destination.
+func (o *convertOptions) options() (string, converter.Options) {
+	srcPaths, err := o.srcMigrPaths.expand()
+	if err != nil {
+		usagef("validate error: %s, run migradaptor convert -help for information", err.Error())
+	}
+	for _, src := range srcPaths {
+		if err := builder.ValidateInput(&o.dstType, &src.path, &o.dstMigrPath); err != nil {
+			usagef("validate error: %s, run migradaptor convert -help for information", err.Error())
+		}
+		if _, err := os.Stat(src.path); os.IsNotExist(err) {
+			usagef("source migration directory %s doesn't exists", src.path)
+		}
 	}
 
-	destType, err := builder.GetDstType(dstType)
+	dstMigrPath, err := filepath.Abs(o.dstMigrPath)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "get dstType type error: %s\n", err.Error())
-		os.Exit(1)
+		fatalf("can't get current directory: %s", err.Error())
+	}
+
+	opts := o.conversion()
+	opts.Recursive = o.recursive
+	opts.Include = o.include
+	opts.Exclude = o.exclude
+	if len(srcPaths) == 1 && srcPaths[0].prefix == "" {
+		sourceRoot = srcPaths[0].path
+		opts.Source = os.DirFS(srcPaths[0].path)
+		opts.Versions = sourceVersions(o.gitVersions, srcPaths[0].path)
+		return dstMigrPath, opts
+	}
+	for _, src := range srcPaths {
+		opts.Sources = append(opts.Sources, converter.SourceDir{
+			Name:     src.path,
+			FS:       os.DirFS(src.path),
+			Prefix:   src.prefix,
+			Versions: sourceVersions(o.gitVersions, src.path),
+		})
 	}
+	return dstMigrPath, opts
+}
 
-	if _, err := os.Stat(srcMigrPath); os.IsNotExist(err) {
-		_, _ = fmt.Fprintf(os.Stderr, "source migration directory %s doesn't exists\n", srcMigrPath)
-		os.Exit(1)
+// conversion returns the options of how the migrations are converted,
+// without their sources.
+func (o *convertOptions) conversion() converter.Options {
+	destType, err := builder.GetDstType(o.dstType)
+	if err != nil {
+		usagef("get dstType type error: %s", err.Error())
 	}
 
-	pwd, err := os.Getwd()
+	versioning, err := builder.GetVersioning(o.versioning)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "can't get current directory: %s\n", err.Error())
-		os.Exit(1)
+		usagef("get versioning error: %s", err.Error())
 	}
-	dstMigrPath = path.Join(pwd, dstMigrPath)
-	srcMigrPath = path.Join(pwd, srcMigrPath)
 
-	if _, err := os.Stat(dstMigrPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(dstMigrPath, os.ModePerm); err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "create dest dir error: %s\n", err.Error())
-			os.Exit(1)
-		}
-	} else {
-		if err := builder.RemoveContents(dstMigrPath); err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "clear dest migrations folder error: %s\n", err.Error())
-			os.Exit(1)
-		}
+	return converter.Options{
+		DstType:      destType,
+		Placeholders: newPlaceholders(o.phMode, o.varsPath),
+		GenerateDown: o.genDown,
+		Versioning:   versioning,
+
+		ContinueOnError: o.continueOnError,
+		WriteValid:      o.writeValid,
 	}
+}
 
-	files, err := os.ReadDir(srcMigrPath)
+// sourceVersions returns the git versions of the folder if asked.
+// Filename versions are used if the git history is unavailable.
+func sourceVersions(gitVersions bool, srcMigrPath string) map[string]int64 {
+	if !gitVersions {
+		return nil
+	}
+	versions, err := builder.GitVersions(srcMigrPath)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "read src migrations folder error: %s\n", err.Error())
-		os.Exit(1)
+		warnf(srcMigrPath, "git history is unavailable, versions are taken from the filenames: %s", err.Error())
+		return nil
 	}
-	maxTime := int64(0)
+	return versions
+}
 
-	for _, file := range files {
-		if !builder.IsSqlMigrationFile(file.Name()) {
-			continue
-		}
+// convert runs the conversion, reporting the diagnostics and the converted versions.
+func convert(opts converter.Options) converter.Result {
+	result, err := converter.Convert(context.Background(), opts)
+	return converted(opts, result, err)
+}
 
-		lf, err := os.Open(path.Join(srcMigrPath, file.Name()))
-		if err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "open src migrations folder error: %s\n", err.Error())
-			os.Exit(1)
+// converted reports the result of a conversion, exiting if it failed.
+func converted(opts converter.Options, result converter.Result, err error) converter.Result {
+	writeReport(result.Report(err))
+	reportSource(result.Diagnostics...)
+	switch {
+	case err == nil:
+	case errors.Is(err, builder.ErrFilesFailed):
+		for _, d := range result.Failures {
+			failures = append(failures, sourceDiagnostic(d))
 		}
-		defer func() {
-			if err := lf.Close(); err != nil {
-				_, _ = fmt.Fprintf(os.Stderr, "closing src migrations folder error: %s\n", err.Error())
-				os.Exit(1)
-			}
-		}()
-
-		lines, err := builder.ReadFileLines(lf)
-		if err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "reading src migrations file lines error: %s\n", err.Error())
-			os.Exit(1)
+		if !opts.WriteValid {
+			exit(exitFailed)
 		}
+	case errors.As(err, new(builder.Diagnostic)):
+		// errors raised for a file are already among the diagnostics
+		exit(exitFailed)
+	default:
+		fatalf("convert error: %s", err.Error())
+	}
+	for _, m := range result.Migrations {
+		reportSource(builder.Diagnostic{
+			Severity: builder.SeverityInfo,
+			Code:     builder.DiagnosticConverted,
+			File:     path.Join(m.SourceDir, m.Source),
+			Message:  fmt.Sprintf("version %d, %s", m.Version, strings.Join(m.Files, ", ")),
+		})
+	}
+	return result
+}
 
-		var upMigr, downMigr []string
-		switch destType {
-		default:
-			upMigr, downMigr = builder.BuildMigrationData(lines)
+// newPlaceholders looks the placeholders up in the vars file if it's given,
+// then in the config vars, and in the environment if there is no vars file.
+func newPlaceholders(phMode, varsPath string) builder.Placeholders {
+	placeholderMode, err := builder.GetPlaceholderMode(phMode)
+	if err != nil {
+		usagef("get placeholders mode error: %s", err.Error())
+	}
+	placeholders := builder.Placeholders{
+		Mode: placeholderMode,
+		Lookup: func(name string) (string, bool) {
+			if value, ok := configVars[name]; ok {
+				return value, true
+			}
+			return os.LookupEnv(name)
+		},
+	}
+	if varsPath == "" {
+		return placeholders
+	}
+	vf, err := os.Open(varsPath)
+	if err != nil {
+		fatalf("open vars file error: %s", err.Error())
+	}
+	vars, err := builder.ReadVarsFile(vf)
+	_ = vf.Close()
+	if err != nil {
+		fatalf("read vars file error: %s", err.Error())
+	}
+	placeholders.Lookup = func(name string) (string, bool) {
+		if value, ok := vars[name]; ok {
+			return value, true
 		}
+		value, ok := configVars[name]
+		return value, ok
+	}
+	return placeholders
+}
 
-		timestamp, name, err := builder.ParseFilename(file.Name())
-		if err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "filename parsing error: %s\n", err.Error())
-			os.Exit(1)
-		}
-		if timestamp <= maxTime {
-			timestamp = maxTime + 1
+func runConvert(args []string) {
+	var (
+		opts        convertOptions
+		dryRun      bool
+		incremental bool
+		force       bool
+		stdin       stdinOptions
+	)
+	fs := newFlagSet("convert", "[options]",
+		"Converts the source migrations to the destination library format, writing them with a manifest\n"+
+			"to the destination folder or archive.\n\n"+
+			"With -from, converts a single migration read from stdin and prints the destination files to stdout,\n"+
+			"no folder is read or written: migradaptor convert -from goose -to golang-migrate < file.sql")
+	opts.register(fs)
+	fs.BoolVar(&dryRun, "dry-run", false, "print the planned files and their diff with the destination folder without writing")
+	fs.BoolVar(&incremental, "incremental", false, "write only new and changed migrations, keeping the files not produced by the converter")
+	fs.BoolVar(&force, "force", false, "with -incremental, overwrite files that differ from what the converter produced")
+	stdin.register(fs)
+	parseFlags(fs, args)
+
+	var stdout []string
+	if stdin.from != "" {
+		stdout = append(stdout, "-from")
+	}
+	if dryRun {
+		stdout = append(stdout, "-dry-run")
+	}
+	checkReportStdout(stdout...)
+
+	if stdin.from != "" {
+		if dryRun || incremental || force {
+			usagef("-dry-run, -incremental and -force are not supported with -from")
 		}
-		maxTime = timestamp
+		convertStdin(&opts, stdin)
+		return
+	}
+	if stdin.to != "" || stdin.name != "" || stdin.output != outputText {
+		usagef("-to, -name and -output are supported only with -from")
+	}
 
-		println(fmt.Sprintf("%d : %s", timestamp, name))
+	dstMigrPath, convertOpts := opts.options()
 
-		upMgrFn := fmt.Sprintf("%d_%s.up.sql", timestamp, name)
-		downMgrFn := fmt.Sprintf("%d_%s.down.sql", timestamp, name)
+	if isArchive(dstMigrPath) && (dryRun || incremental) {
+		usagef("-dry-run and -incremental are supported only for destination folders")
+	}
 
-		// create migration .up file
-		if err := builder.CreateAndWrite(dstMigrPath, upMgrFn, upMigr); err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "writing destination migrations error: %s\n", err.Error())
-			os.Exit(1)
-		}
-		// migration .down file
-		if err := builder.CreateAndWrite(dstMigrPath, downMgrFn, downMigr); err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "writing destination migrations error: %s\n", err.Error())
-			os.Exit(1)
+	if dryRun || incremental {
+		result := convert(convertOpts)
+		if dryRun {
+			printDryRun(dstMigrPath, result.Files, incremental)
+			return
 		}
+		writeIncremental(dstMigrPath, result, force)
+		return
 	}
 
-	println("finished")
+	write(dstMigrPath, convertOpts)
 }
 
-func PrintHelp() {
-	helpText := `
-Usage: migradaptor [options] ...
+// write replaces the contents of the destination folder or archive with the
+// converted migrations and the manifest.
+func write(dstMigrPath string, convertOpts converter.Options) {
+	if isArchive(dstMigrPath) {
+		writeArchive(dstMigrPath, convert(convertOpts))
+		return
+	}
+
+	sink := &converter.DirSink{Dir: dstMigrPath, Clean: true}
+	convertOpts.Destination = sink
+	result := convert(convertOpts)
 
-  Migrate your sql migrations files between different lib formats.
+	content, err := result.Manifest().Marshal()
+	if err == nil {
+		err = sink.WriteFile(builder.ManifestFilename, content)
+	}
+	if err != nil {
+		fatalf("writing manifest error: %s", err.Error())
+	}
+}
+
+func isArchive(dstMigrPath string) bool {
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(dstMigrPath, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeArchive writes the files to a tar, gzipped tar or zip archive depending on the extension.
+func writeArchive(dstMigrPath string, result converter.Result) {
+	af, err := os.Create(dstMigrPath)
+	if err != nil {
+		fatalf("create dest archive error: %s", err.Error())
+	}
+	var (
+		sink    converter.Sink
+		closers []io.Closer
+	)
+	switch {
+	case strings.HasSuffix(dstMigrPath, ".zip"):
+		zs := converter.NewZipSink(af)
+		sink, closers = zs, []io.Closer{zs, af}
+	case strings.HasSuffix(dstMigrPath, ".tar"):
+		ts := converter.NewTarSink(af)
+		sink, closers = ts, []io.Closer{ts, af}
+	default:
+		gw := gzip.NewWriter(af)
+		ts := converter.NewTarSink(gw)
+		sink, closers = ts, []io.Closer{ts, gw, af}
+	}
+	manifest, err := result.Manifest().Marshal()
+	if err != nil {
+		fatalf("writing manifest error: %s", err.Error())
+	}
+	files := append(result.Files, builder.File{Name: builder.ManifestFilename, Content: manifest})
+	for _, f := range files {
+		if err := sink.WriteFile(f.Name, f.Content); err != nil {
+			fatalf("writing destination migrations error: %s", err.Error())
+		}
+	}
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			fatalf("closing dest archive error: %s", err.Error())
+		}
+	}
+}
 
-Options:
+func writeIncremental(dstMigrPath string, result converter.Result, force bool) {
+	if err := os.MkdirAll(dstMigrPath, os.ModePerm); err != nil {
+		fatalf("create dest dir error: %s", err.Error())
+	}
+	manifest, err := builder.ReadManifest(dstMigrPath)
+	if err != nil {
+		fatalf("read manifest error: %s", err.Error())
+	}
+	manifest.Migrations = result.Manifest().Migrations
+	plan, err := builder.PlanIncremental(dstMigrPath, result.Files, manifest, force)
+	if err != nil {
+		fatalf("read dest migrations folder error: %s", err.Error())
+	}
+	for _, name := range plan.Clashes {
+		report(builder.Diagnostic{Severity: builder.SeverityError, File: name, Message: "not produced by the converter, but has the version of a converted migration"})
+	}
+	if len(plan.Clashes) != 0 {
+		fatalf("nothing is written, rename the files so the versions are unique")
+	}
+	if len(plan.Conflicts) != 0 {
+		for _, name := range plan.Conflicts {
+			report(builder.Diagnostic{Severity: builder.SeverityError, File: name, Message: "differs from what the converter produced"})
+		}
+		fatalf("nothing is written, use -force to overwrite")
+	}
+	for _, name := range plan.Kept {
+		warnf(name, "kept: not produced anymore, but was modified")
+	}
+	if err := builder.ApplyIncremental(dstMigrPath, result.Files, plan, manifest); err != nil {
+		fatalf("writing destination migrations error: %s", err.Error())
+	}
+}
 
-  -source-type=rubenv-sql-migration   Source library of sql files, that need to transform.
-  -src="source migrations path"       Source migrations folder.
-  -dst="destination migrations path"  Destination migrations folder.
-`
-	println(helpText)
+func printDryRun(dstMigrPath string, files []builder.File, incremental bool) {
+	changes, err := builder.CompareDir(dstMigrPath, files)
+	if err != nil {
+		fatalf("read dest migrations folder error: %s", err.Error())
+	}
+	if incremental {
+		manifest, err := builder.ReadManifest(dstMigrPath)
+		if err != nil {
+			fatalf("read manifest error: %s", err.Error())
+		}
+		owned := changes[:0]
+		for _, change := range changes {
+			if _, ok := manifest.Files[change.Name]; ok || change.Status != builder.FileStatusRemoved {
+				owned = append(owned, change)
+			}
+		}
+		changes = owned
+	}
+	for _, change := range changes {
+		fmt.Printf("%-9s %s\n", change.Status, change.Name)
+	}
+	for _, change := range changes {
+		if change.Status == builder.FileStatusUnchanged {
+			continue
+		}
+		diff, err := builder.UnifiedDiff(change)
+		if err != nil {
+			fatalf("diff error: %s", err.Error())
+		}
+		fmt.Print(diff)
+	}
 }
 
 func GetVersion() string {
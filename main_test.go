@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+// mainEnv makes the test binary run main with its arguments, see runMain.
+const mainEnv = "MIGRADAPTOR_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(mainEnv) == "1" {
+		os.Args = append([]string{"migradaptor"}, os.Args[1:]...)
+		main()
+		os.Exit(exitOK)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the command in dir in a subprocess, as main exits, and
+// returns its exit code, stdout and stderr.
+func runMain(t *testing.T, dir, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), mainEnv+"=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), stdout.String(), stderr.String()
+	}
+	require.NoError(t, err)
+	return exitOK, stdout.String(), stderr.String()
+}
+
+// writeFiles writes the files to a temporary folder and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), os.ModePerm))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestParseFlags_Config(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"migradaptor.yaml": "src: [db/migrations, billing=db/billing]\ndst: migrations\nversioning: timestamp\n" +
+			"vars:\n  SCHEMA: config\n  ROLE: config\n",
+		"vars.env": "SCHEMA=flag\n",
+	})
+	t.Cleanup(func() { configPath, configVars = "", nil })
+
+	var opts convertOptions
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	opts.register(fs)
+	parseFlags(fs, []string{
+		"-config", filepath.Join(dir, "migradaptor.yaml"),
+		"-dst", "out",
+		"-vars-file", filepath.Join(dir, "vars.env"),
+	})
+	require.Equal(t, sourcePaths{"db/migrations", "billing=db/billing"}, opts.srcMigrPaths)
+	require.Equal(t, "out", opts.dstMigrPath)
+	require.Equal(t, "timestamp", opts.versioning)
+
+	lookup := newPlaceholders("substitute", opts.varsPath).Lookup
+	value, _ := lookup("SCHEMA")
+	require.Equal(t, "flag", value)
+	value, _ = lookup("ROLE")
+	require.Equal(t, "config", value)
+
+	t.Setenv("ROLE", "env")
+	t.Setenv("OWNER", "env")
+	lookup = newPlaceholders("substitute", "").Lookup
+	value, _ = lookup("ROLE")
+	require.Equal(t, "config", value)
+	value, _ = lookup("OWNER")
+	require.Equal(t, "env", value)
+
+	opts = convertOptions{}
+	fs = flag.NewFlagSet("convert", flag.ContinueOnError)
+	opts.register(fs)
+	parseFlags(fs, []string{"-config", filepath.Join(dir, "migradaptor.yaml"), "-src", "other"})
+	require.Equal(t, sourcePaths{"other"}, opts.srcMigrPaths)
+	require.Equal(t, "migrations", opts.dstMigrPath)
+}
+
+func TestParseFlags_SingleSourceConfig(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"migradaptor.yaml": "src: [db/migrations, billing=db/billing]\n",
+		"src/1_users.sql":  "-- +goose Up\nCREATE TABLE users (id int);\n",
+	})
+
+	code, _, stderr := runMain(t, dir, "", "lint")
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr, "config error: src: lint takes a single source folder")
+
+	code, _, _ = runMain(t, dir, "", "detect", "-src", "src")
+	require.Equal(t, exitOK, code)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "migradaptor.yaml"), []byte("src: [src]\n"), 0o644))
+	code, stdout, _ := runMain(t, dir, "", "detect")
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "goose\t"+filepath.Join("src", "1_users.sql")+"\n", stdout)
+}
+
+const usersMigration = "-- +goose Up\nCREATE TABLE users (id int);\n-- +goose Down\nDROP TABLE users;\n"
+
+func TestMain_Commands(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"src/1_users.sql": usersMigration})
+
+	code, stdout, _ := runMain(t, dir, "", "help")
+	require.Equal(t, exitOK, code)
+	for _, cmd := range commands {
+		require.Contains(t, stdout, cmd.name)
+	}
+
+	code, _, stderr := runMain(t, dir, "", "bogus")
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr, `unknown command "bogus"`)
+
+	code, _, _ = runMain(t, dir, "", "convert", "-versioning", "bogus")
+	require.Equal(t, exitUsage, code)
+
+	// no arguments convert src to dst as before the commands
+	code, _, _ = runMain(t, dir, "")
+	require.Equal(t, exitOK, code)
+	require.FileExists(t, filepath.Join(dir, "dst", "1_users.up.sql"))
+
+	code, _, _ = runMain(t, dir, "", "-dst", "out")
+	require.Equal(t, exitOK, code)
+	require.FileExists(t, filepath.Join(dir, "out", "1_users.up.sql"))
+
+	code, stdout, _ = runMain(t, dir, "", "check")
+	require.Equal(t, exitOK, code)
+	require.Empty(t, stdout)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "2_index.sql"),
+		[]byte("-- +goose Up\nCREATE INDEX CONCURRENTLY users_id_idx ON users (id);\n"), 0o644))
+	code, stdout, _ = runMain(t, dir, "", "check")
+	require.Equal(t, exitFailed, code)
+	require.Contains(t, stdout, "missing  2_index.up.sql")
+
+	code, _, stderr = runMain(t, dir, "", "lint")
+	require.Equal(t, exitFailed, code)
+	require.Contains(t, stderr, filepath.Join("src", "2_index.sql")+":2: error: concurrent-index-in-transaction")
+
+	code, _, _ = runMain(t, dir, "", "lint", "-rule", "concurrent-index-in-transaction=warning")
+	require.Equal(t, exitOK, code)
+}
+
+func TestMain_New(t *testing.T) {
+	dir := t.TempDir()
+
+	code, stdout, _ := runMain(t, dir, "", "new", "-versioning", "sequential", "add_users", "-dst", "out")
+	require.Equal(t, exitOK, code)
+	require.Equal(t, filepath.Join("out", "000001_add_users.up.sql")+"\n"+filepath.Join("out", "000001_add_users.down.sql")+"\n", stdout)
+
+	code, _, stderr := runMain(t, dir, "", "new", "add_x", "add_y")
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr, "unexpected arguments after the name: add_y")
+
+	code, _, _ = runMain(t, dir, "", "new")
+	require.Equal(t, exitUsage, code)
+}
+
+func TestMain_DryRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"src/1_users.sql": usersMigration})
+
+	code, stdout, _ := runMain(t, dir, "", "convert", "-dry-run")
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, "new       1_users.up.sql\nnew       1_users.down.sql\n")
+	require.Contains(t, stdout, "+++ b/1_users.up.sql\n@@ -0,0 +1,5 @@\n+BEGIN;\n")
+	require.NoDirExists(t, filepath.Join(dir, "dst"))
+}
+
+func TestMain_VersionClash(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"src/1_users.sql": usersMigration})
+
+	code, _, _ := runMain(t, dir, "", "convert", "-incremental")
+	require.Equal(t, exitOK, code)
+	code, _, _ = runMain(t, dir, "", "new", "add_x", "-dst", "dst")
+	require.Equal(t, exitOK, code)
+	require.FileExists(t, filepath.Join(dir, "dst", "2_add_x.up.sql"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "2_index.sql"),
+		[]byte("-- +goose Up\nCREATE INDEX users_id_idx ON users (id);\n"), 0o644))
+	code, _, stderr := runMain(t, dir, "", "convert", "-incremental", "-force")
+	require.Equal(t, exitFailed, code)
+	require.Contains(t, stderr, "2_add_x.up.sql: error: not produced by the converter, but has the version of a converted migration")
+	require.NoFileExists(t, filepath.Join(dir, "dst", "2_index.up.sql"))
+
+	code, stdout, _ := runMain(t, dir, "", "check")
+	require.Equal(t, exitFailed, code)
+	require.Contains(t, stdout, "clash    2_add_x.up.sql")
+}
+
+func TestMain_ReportStdout(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"src/1_users.sql": usersMigration})
+
+	code, stdout, _ := runMain(t, dir, "", "convert", "-report", "json")
+	require.Equal(t, exitOK, code)
+	require.True(t, json.Valid([]byte(stdout)))
+
+	for _, args := range [][]string{
+		{"convert", "-report", "json", "-dry-run"},
+		{"convert", "-report", "json", "-from", "goose"},
+		{"convert", "-report", "json", "-diagnostics", "github"},
+		{"check", "-report", "json"},
+	} {
+		// github annotations of the error are printed to stdout
+		code, stdout, stderr := runMain(t, dir, usersMigration, args...)
+		require.Equal(t, exitUsage, code, args)
+		require.Contains(t, stdout+stderr, "-report json can't be used with", args)
+	}
+}
+
+func TestMain_Stdin(t *testing.T) {
+	dir := t.TempDir()
+
+	code, stdout, _ := runMain(t, dir, usersMigration, "convert", "-from", "goose", "-to", "golang-migrate")
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "-- 1_stdin.up.sql\nBEGIN;\n\nCREATE TABLE users (id int);\nCOMMIT;\n\n\n"+
+		"-- 1_stdin.down.sql\nBEGIN;\n\nDROP TABLE users;\n\nCOMMIT;\n\n", stdout)
+
+	code, stdout, _ = runMain(t, dir, usersMigration, "convert", "-from", "auto", "-name", "20230101000000_users.sql", "-output", "json")
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, `"name": "20230101000000_users.up.sql"`)
+
+	code, _, stderr := runMain(t, dir, usersMigration, "convert", "-from", "dbmate")
+	require.Equal(t, exitFailed, code)
+	require.Contains(t, stderr, "stdin is a goose migration, not dbmate")
+
+	code, _, _ = runMain(t, dir, usersMigration, "convert", "-from", "goose", "-dry-run")
+	require.Equal(t, exitUsage, code)
+}
+
+func TestRuleSeverities_String(t *testing.T) {
+	severities := ruleSeverities(builder.DefaultLintSeverities())
+	require.Equal(t, "concurrent-index-in-transaction=error,down-not-reverting=warning,drop-without-if-exists=warning,"+
+		"duplicate-version=error,missing-down=warning,non-idempotent-enum=warning", severities.String())
+}
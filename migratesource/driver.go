@@ -0,0 +1,174 @@
+// Package migratesource implements a golang-migrate source driver that reads
+// goose, sql-migrate, dbmate and go-pg migrations folders directly,
+// converting them in memory.
+//
+// Importing the package registers the driver under the goose, sqlmigrate,
+// dbmate and gopg schemes, e.g. goose://path/to/migrations. The source format
+// is detected from the files and must match the scheme, plain sql files are
+// accepted by any of them.
+package migratesource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/pkg/errors"
+
+	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
+)
+
+var Schemes = []string{"goose", "sqlmigrate", "dbmate", "gopg"}
+
+// schemeFormats are the source formats served under each of the Schemes.
+var schemeFormats = map[string][]builder.SourceFormat{
+	"goose":      {builder.SourceFormatGoose, builder.SourceFormatGooseGo},
+	"sqlmigrate": {builder.SourceFormatSqlMigrate},
+	"dbmate":     {builder.SourceFormatDbmate},
+	"gopg":       {builder.SourceFormatGoPg},
+}
+
+func init() {
+	for _, scheme := range Schemes {
+		source.Register(scheme, &Driver{})
+	}
+}
+
+// Driver serves the converted migrations as golang-migrate ones.
+type Driver struct {
+	path       string
+	migrations *source.Migrations
+	// contents of the converted files by name
+	contents map[string][]byte
+}
+
+// New converts the migrations found in fsys. DstType defaults to golang-migrate,
+// Source and Destination of the options are ignored.
+func New(fsys fs.FS, opts converter.Options) (*Driver, error) {
+	d, _, err := newDriver(fsys, opts)
+	return d, err
+}
+
+func newDriver(fsys fs.FS, opts converter.Options) (*Driver, []converter.Migration, error) {
+	opts.Source = fsys
+	opts.Destination = nil
+	if opts.DstType == "" {
+		opts.DstType = builder.DstTypeSqlMigrate
+	}
+	result, err := converter.Convert(context.Background(), opts)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "convert source migrations")
+	}
+
+	contents := make(map[string][]byte, len(result.Files))
+	for _, f := range result.Files {
+		contents[f.Name] = f.Content
+	}
+	d := &Driver{
+		path:       "<fs>",
+		migrations: source.NewMigrations(),
+		contents:   contents,
+	}
+	for _, m := range result.Migrations {
+		for i, direction := range []source.Direction{source.Up, source.Down} {
+			name := m.Files[i]
+			content := contents[name]
+			if direction == source.Down && len(bytes.TrimSpace(content)) == 0 {
+				continue
+			}
+			if !d.migrations.Append(&source.Migration{
+				Version:    uint(m.Version),
+				Identifier: m.Name,
+				Direction:  direction,
+				Raw:        name,
+			}) {
+				return nil, nil, fmt.Errorf("duplicate migration file: %s", name)
+			}
+		}
+	}
+	return d, result.Migrations, nil
+}
+
+// Open converts the folder of a scheme://path url, the query is ignored.
+func (d *Driver) Open(rawURL string) (source.Driver, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse source url")
+	}
+	formats, ok := schemeFormats[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported source url: %s", rawURL)
+	}
+	pth := u.Host + u.Path
+	if pth == "" {
+		pth = "."
+	}
+	driver, migrations, err := newDriver(os.DirFS(pth), converter.Options{})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range migrations {
+		if m.Format != builder.SourceFormatPlain && !isFormat(formats, m.Format) {
+			return nil, fmt.Errorf("%s is a %s migration, not %s", m.Source, m.Format, u.Scheme)
+		}
+	}
+	driver.path = pth
+	return driver, nil
+}
+
+func (d *Driver) Close() error {
+	return nil
+}
+
+func (d *Driver) First() (uint, error) {
+	version, ok := d.migrations.First()
+	if !ok {
+		return 0, &fs.PathError{Op: "first", Path: d.path, Err: fs.ErrNotExist}
+	}
+	return version, nil
+}
+
+func (d *Driver) Prev(version uint) (uint, error) {
+	prev, ok := d.migrations.Prev(version)
+	if !ok {
+		return 0, &fs.PathError{Op: fmt.Sprintf("prev for version %d", version), Path: d.path, Err: fs.ErrNotExist}
+	}
+	return prev, nil
+}
+
+func (d *Driver) Next(version uint) (uint, error) {
+	next, ok := d.migrations.Next(version)
+	if !ok {
+		return 0, &fs.PathError{Op: fmt.Sprintf("next for version %d", version), Path: d.path, Err: fs.ErrNotExist}
+	}
+	return next, nil
+}
+
+func (d *Driver) ReadUp(version uint) (io.ReadCloser, string, error) {
+	if m, ok := d.migrations.Up(version); ok {
+		return io.NopCloser(bytes.NewReader(d.contents[m.Raw])), m.Identifier, nil
+	}
+	return nil, "", &fs.PathError{Op: fmt.Sprintf("read up for version %d", version), Path: d.path, Err: fs.ErrNotExist}
+}
+
+func (d *Driver) ReadDown(version uint) (io.ReadCloser, string, error) {
+	if m, ok := d.migrations.Down(version); ok {
+		return io.NopCloser(bytes.NewReader(d.contents[m.Raw])), m.Identifier, nil
+	}
+	return nil, "", &fs.PathError{Op: fmt.Sprintf("read down for version %d", version), Path: d.path, Err: fs.ErrNotExist}
+}
+
+func isFormat(formats []builder.SourceFormat, format builder.SourceFormat) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
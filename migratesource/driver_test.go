@@ -0,0 +1,79 @@
+package migratesource_test
+
+import (
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/converter"
+	"github.com/musinit/migradaptor/migratesource"
+)
+
+func TestDriver(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"20230101000000_companies.sql": {Data: []byte("-- +goose Up\nCREATE TABLE companies (id int);\n-- +goose Down\nDROP TABLE companies;\n")},
+		"20230101000000_users.sql":     {Data: []byte("-- +goose Up\nCREATE TABLE users (id int);\n")},
+	}
+	d, err := migratesource.New(src, converter.Options{})
+	require.NoError(t, err)
+
+	first, err := d.First()
+	require.NoError(t, err)
+	require.EqualValues(t, 20230101000000, first)
+
+	next, err := d.Next(first)
+	require.NoError(t, err)
+	require.EqualValues(t, 20230101000001, next)
+	_, err = d.Next(next)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	prev, err := d.Prev(next)
+	require.NoError(t, err)
+	require.Equal(t, first, prev)
+	_, err = d.Prev(first)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	r, identifier, err := d.ReadUp(first)
+	require.NoError(t, err)
+	require.Equal(t, "companies", identifier)
+	body, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "BEGIN;\n\nCREATE TABLE companies (id int);\nCOMMIT;\n\n", string(body))
+
+	r, _, err = d.ReadDown(first)
+	require.NoError(t, err)
+	body, err = io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "BEGIN;\n\nDROP TABLE companies;\n\nCOMMIT;\n\n", string(body))
+
+	_, _, err = d.ReadDown(next)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, _, err = d.ReadUp(1)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestDriver_Registered(t *testing.T) {
+	t.Parallel()
+	for _, scheme := range migratesource.Schemes {
+		require.Contains(t, source.List(), scheme)
+	}
+	_, err := source.Open("sqlmigrate://testdata-missing")
+	require.Error(t, err)
+}
+
+func TestDriver_Open(t *testing.T) {
+	t.Parallel()
+	d, err := source.Open("goose://testdata/goose?x-foo=1")
+	require.NoError(t, err)
+	first, err := d.First()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, first)
+
+	_, err = source.Open("dbmate://testdata/goose")
+	require.ErrorContains(t, err, "1_users.sql is a goose migration, not dbmate")
+}
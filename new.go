@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func runNew(args []string) {
+	var (
+		dstMigrPath string
+		versioning  string
+	)
+	fs := newFlagSet("new", "[options] name [options]",
+		"Creates empty up and down golang-migrate files for a migration written after the conversion\n"+
+			"and prints their paths. The version follows the ones of the destination folder.")
+	fs.StringVar(&dstMigrPath, "dst", "dst", "destination migrations folder")
+	fs.StringVar(&versioning, "versioning", "", "version of the migration: timestamp or sequential, the one of the folder if not set")
+	registerDiagnostics(fs)
+	registerConfig(fs)
+	parseFlags(fs, args)
+
+	if fs.NArg() == 0 {
+		usagef("validate error: %s, one name argument is expected", builder.ErrNoMigrationName.Error())
+	}
+	arg := fs.Arg(0)
+	// the flag package stops at the name, options may follow it too
+	_ = fs.Parse(fs.Args()[1:])
+	if fs.NArg() != 0 {
+		usagef("validate error: unexpected arguments after the name: %s", strings.Join(fs.Args(), " "))
+	}
+	name, err := builder.MigrationName(arg)
+	if err != nil {
+		usagef("validate error: %s", err.Error())
+	}
+	if dstMigrPath == "" {
+		usagef("validate error: %s", builder.ErrNoDstFolderPath.Error())
+	}
+	versioningType, err := builder.GetVersioning(versioning)
+	if err != nil {
+		usagef("get versioning error: %s", err.Error())
+	}
+
+	entries, err := os.ReadDir(dstMigrPath)
+	if err != nil && !os.IsNotExist(err) {
+		fatalf("read dest migrations folder error: %s", err.Error())
+	}
+	filenames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		filenames = append(filenames, entry.Name())
+	}
+	version, err := builder.NewMigrationVersion(filenames, versioningType, time.Now())
+	if err != nil {
+		fatalf("new migration version error: %s", err.Error())
+	}
+
+	if err := os.MkdirAll(dstMigrPath, os.ModePerm); err != nil {
+		fatalf("create dest dir error: %s", err.Error())
+	}
+	for _, direction := range []string{"up", "down"} {
+		filename := filepath.Join(dstMigrPath, builder.MigrationFilename(version, name, direction))
+		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			fatalf("create migration error: %s", err.Error())
+		}
+		if err := f.Close(); err != nil {
+			fatalf("create migration error: %s", err.Error())
+		}
+		fmt.Println(filename)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
+)
+
+// reportStdout is the -report value printing the report to stdout.
+const reportStdout = "json"
+
+// reportPath is where the -report of the conversion is written.
+var reportPath string
+
+// registerReport adds the -report flag to the command flags.
+func registerReport(fs *flag.FlagSet) {
+	fs.StringVar(&reportPath, "report", "", "write the json report of the run: json for stdout or a file path")
+}
+
+// writeReport writes the report of the conversion if it's asked.
+func writeReport(report converter.Report) {
+	if reportPath == "" {
+		return
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fatalf("encode report error: %s", err.Error())
+	}
+	content = append(content, '\n')
+	if reportPath == reportStdout {
+		_, err = os.Stdout.Write(content)
+	} else {
+		err = os.WriteFile(reportPath, content, 0o644)
+	}
+	if err != nil {
+		fatalf("write report error: %s", err.Error())
+	}
+}
+
+// checkReportStdout exits if -report json is asked while the outputs, or the
+// github diagnostics, are printed to stdout too: the report would be mixed
+// with them.
+func checkReportStdout(outputs ...string) {
+	if reportPath != reportStdout {
+		return
+	}
+	if diagnosticsFormat == builder.ReportFormatGitHub {
+		outputs = append(outputs, "-diagnostics github")
+	}
+	if len(outputs) != 0 {
+		usagef("-report json can't be used with %s printing to stdout, write the report to a file", strings.Join(outputs, " and "))
+	}
+}
@@ -0,0 +1,91 @@
+// Package sqlmigratesource implements a rubenv/sql-migrate MigrationSource
+// over any migrations folder supported by the converter.
+package sqlmigratesource
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+
+	migrate "github.com/rubenv/sql-migrate"
+
+	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
+)
+
+// Source parses the migrations of FS with the Options of the conversion,
+// Source and Destination of the options are ignored.
+type Source struct {
+	FS      fs.FS
+	Options converter.Options
+}
+
+var _ migrate.MigrationSource = Source{}
+
+var (
+	beginReg  = regexp.MustCompile(`(?i)^(BEGIN|START)(\s+(TRANSACTION|WORK))?$`)
+	commitReg = regexp.MustCompile(`(?i)^COMMIT(\s+(TRANSACTION|WORK))?$`)
+)
+
+// FindMigrations returns the migrations in the order they have to be applied,
+// with the same versions the conversion would give them. It fails if any
+// file fails, even in ContinueOnError mode.
+func (s Source) FindMigrations() ([]*migrate.Migration, error) {
+	migrations, _, err := s.Load()
+	return migrations, err
+}
+
+// Load is FindMigrations returning the diagnostics raised while loading the
+// migrations, the warnings among them.
+func (s Source) Load() ([]*migrate.Migration, builder.Diagnostics, error) {
+	opts := s.Options
+	opts.Source = s.FS
+	migrations, diags, err := converter.LoadMigrations(context.Background(), opts)
+	if err != nil {
+		return nil, diags, err
+	}
+	// an incomplete set of migrations must not be applied
+	if err := diags.Failures().Err(); err != nil {
+		return nil, diags, err
+	}
+
+	versions, err := converter.AssignVersions(migrations, opts)
+	if err != nil {
+		return nil, diags, err
+	}
+
+	result := make([]*migrate.Migration, 0, len(migrations))
+	for i, m := range migrations {
+		if opts.GenerateDown {
+			m.Data.GenerateDown()
+		}
+		up, upTx := statements(m.Data.Up)
+		down, downTx := statements(m.Data.Down)
+		result = append(result, &migrate.Migration{
+			Id:                     fmt.Sprintf("%s_%s.sql", opts.Versioning.FormatVersion(versions[i]), m.Name),
+			Up:                     up,
+			Down:                   down,
+			DisableTransactionUp:   !upTx,
+			DisableTransactionDown: !downTx,
+		})
+	}
+	return result, diags, nil
+}
+
+// statements splits the section the way sql-migrate does, keeping the
+// semicolons. The BEGIN and COMMIT wrapping a golang-migrate section are
+// removed and it's run in a transaction instead: sql-migrate executes each
+// statement separately, on any connection of the pool.
+func statements(section builder.Section) ([]string, bool) {
+	split := builder.SplitStatements(section.Lines)
+	transaction := section.Transaction
+	if !transaction && len(split) >= 2 && beginReg.MatchString(split[0]) && commitReg.MatchString(split[len(split)-1]) {
+		split, transaction = split[1:len(split)-1], true
+	}
+	result := make([]string, 0, len(split))
+	for _, statement := range split {
+		result = append(result, statement+";")
+	}
+	return result, transaction
+}
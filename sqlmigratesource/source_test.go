@@ -0,0 +1,107 @@
+package sqlmigratesource_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
+	"github.com/musinit/migradaptor/sqlmigratesource"
+)
+
+func TestSource_FindMigrations(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1_companies.tx.up.sql":   {Data: []byte("CREATE TABLE companies (id int);\nINSERT INTO companies VALUES (1);\n")},
+		"1_companies.tx.down.sql": {Data: []byte("DROP TABLE companies;\n")},
+		"2_users.up.sql":          {Data: []byte("CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;\n")},
+	}
+
+	migrations, err := sqlmigratesource.Source{FS: src}.FindMigrations()
+	require.NoError(t, err)
+	require.Equal(t, []*migrate.Migration{
+		{
+			Id:   "1_companies.sql",
+			Up:   []string{"CREATE TABLE companies (id int);", "INSERT INTO companies VALUES (1);"},
+			Down: []string{"DROP TABLE companies;"},
+		},
+		{
+			Id:                     "2_users.sql",
+			Up:                     []string{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;"},
+			Down:                   []string{},
+			DisableTransactionUp:   true,
+			DisableTransactionDown: true,
+		},
+	}, migrations)
+}
+
+func TestSource_NoTransaction(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"20230101000000-users.sql": {Data: []byte("-- +migrate Up notransaction\nCREATE INDEX CONCURRENTLY users_id_idx ON users (id);\n-- +migrate Down\nDROP INDEX users_id_idx;\n")},
+	}
+
+	migrations, err := sqlmigratesource.Source{FS: src}.FindMigrations()
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	require.True(t, migrations[0].DisableTransactionUp)
+	require.False(t, migrations[0].DisableTransactionDown)
+	require.Equal(t, 20230101000000, int(migrations[0].VersionInt()))
+}
+
+func TestSource_ContinueOnError(t *testing.T) {
+	t.Parallel()
+	src := fstest.MapFS{
+		"1-companies.sql": {Data: []byte("-- +migrate Up\nCREATE TABLE companies (id int);\nSELECT '${MISSING}';\n")},
+		"companies.sql":   {Data: []byte("-- +migrate Up\nCREATE TABLE users (id int);\n")},
+	}
+	source := sqlmigratesource.Source{FS: src, Options: converter.Options{ContinueOnError: true}}
+
+	migrations, err := source.FindMigrations()
+	require.ErrorIs(t, err, builder.ErrFilesFailed)
+	require.Nil(t, migrations)
+
+	delete(src, "companies.sql")
+	migrations, diags, err := source.Load()
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	require.Equal(t, []string{"unresolved placeholder ${MISSING} in 1-companies.sql"}, diags.Messages(builder.SeverityWarning))
+}
+
+func TestSource_GolangMigrate(t *testing.T) {
+	t.Parallel()
+	sink := converter.MemorySink{}
+	_, err := converter.Convert(context.Background(), converter.Options{
+		Source: fstest.MapFS{
+			"1_companies.sql": {Data: []byte("-- +goose Up\nCREATE TABLE companies (id int);\nINSERT INTO companies VALUES (1);\n-- +goose Down\nDROP TABLE companies;\n")},
+			"2_index.sql":     {Data: []byte("-- +goose Up NO TRANSACTION\nCREATE INDEX CONCURRENTLY companies_id_idx ON companies (id);\n")},
+		},
+		Destination: sink,
+	})
+	require.NoError(t, err)
+	src := fstest.MapFS{}
+	for name, content := range sink {
+		src[name] = &fstest.MapFile{Data: content}
+	}
+
+	migrations, err := sqlmigratesource.Source{FS: src}.FindMigrations()
+	require.NoError(t, err)
+	require.Equal(t, []*migrate.Migration{
+		{
+			Id:   "1_companies.sql",
+			Up:   []string{"CREATE TABLE companies (id int);", "INSERT INTO companies VALUES (1);"},
+			Down: []string{"DROP TABLE companies;"},
+		},
+		{
+			Id:                     "2_index.sql",
+			Up:                     []string{"CREATE INDEX CONCURRENTLY companies_id_idx ON companies (id);"},
+			Down:                   []string{},
+			DisableTransactionUp:   true,
+			DisableTransactionDown: true,
+		},
+	}, migrations)
+}
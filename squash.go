@@ -0,0 +1,27 @@
+package main
+
+import "github.com/musinit/migradaptor/converter"
+
+func runSquash(args []string) {
+	var (
+		opts converter.SquashRange
+		conv convertOptions
+	)
+	fs := newFlagSet("squash", "-from=version -to=version [options]",
+		"Converts the source migrations folding the ones with destination versions in the range into one.\n"+
+			"Migrations with non-transactional sections are kept in their own parts, the parts take the last versions\n"+
+			"of the range. The squashed versions are recorded in the manifest.")
+	conv.register(fs)
+	fs.Int64Var(&opts.From, "from", 0, "first destination version to squash")
+	fs.Int64Var(&opts.To, "to", 0, "last destination version to squash")
+	fs.StringVar(&opts.Name, "name", converter.DefaultSquashName, "name of the squashed migration")
+	parseFlags(fs, args)
+	checkReportStdout()
+
+	if opts.From > opts.To {
+		usagef("validate error: -from %d is greater than -to %d", opts.From, opts.To)
+	}
+	dstMigrPath, convertOpts := conv.options()
+	convertOpts.Squash = &opts
+	write(dstMigrPath, convertOpts)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
+)
+
+func runStamp(args []string) {
+	var (
+		conv    convertOptions
+		from    string
+		dialect string
+		table   string
+		outPath string
+	)
+	fs := newFlagSet("stamp", "-from=goose [options]",
+		"Generates the sql that fills the golang-migrate history table from the source one,\n"+
+			"with the versions recorded in the manifest of the destination folder. If it has none,\n"+
+			"the versions the conversion gives to the source migrations with the same options are used.")
+	conv.register(fs)
+	fs.StringVar(&from, "from", "", "library that filled the source history table: goose, sql-migrate or dbmate")
+	fs.StringVar(&dialect, "dialect", string(builder.DialectPostgres), "sql dialect: postgres, mysql or sqlite")
+	fs.StringVar(&table, "table", "schema_migrations", "golang-migrate history table")
+	fs.StringVar(&outPath, "out", "", "file to write the sql to, stdout is used if not set")
+	parseFlags(fs, args)
+	var stdout []string
+	if outPath == "" {
+		stdout = append(stdout, "stamp without -out")
+	}
+	checkReportStdout(stdout...)
+
+	source, err := builder.GetHistorySource(from)
+	if err != nil {
+		usagef("get history source error: %s", err.Error())
+	}
+	sqlDialect, err := builder.GetDialect(dialect)
+	if err != nil {
+		usagef("get dialect error: %s", err.Error())
+	}
+	manifest, err := builder.ReadManifest(conv.dstMigrPath)
+	if err != nil {
+		fatalf("read manifest error: %s", err.Error())
+	}
+	migrations := manifest.Migrations
+	if len(migrations) == 0 {
+		_, convertOpts := conv.options()
+		migrations = convert(convertOpts).Manifest().Migrations
+	}
+	sql, err := builder.StampSQL(source, sqlDialect, table, converter.StampVersions(source, migrations))
+	if err != nil {
+		fatalf("generate stamp sql error: %s", err.Error())
+	}
+
+	if outPath == "" {
+		fmt.Print(sql)
+		return
+	}
+	if err := os.WriteFile(outPath, []byte(sql), 0o644); err != nil {
+		fatalf("write stamp sql error: %s", err.Error())
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/musinit/migradaptor/builder"
+	"github.com/musinit/migradaptor/converter"
+)
+
+const (
+	outputText = "text"
+	outputTar  = "tar"
+	outputJSON = "json"
+
+	// formatAuto is the -from value detecting the format of the migration.
+	formatAuto = "auto"
+)
+
+// stdinFilenames are the default source filenames of a migration read from
+// stdin, the ones not listed are sql files.
+var stdinFilenames = map[builder.SourceFormat]string{
+	builder.SourceFormatGooseGo: "1_stdin.go",
+	builder.SourceFormatGoPg:    "1_stdin.up.sql",
+}
+
+// stdinOptions are the convert flags of a single migration read from stdin.
+type stdinOptions struct {
+	from   string
+	to     string
+	name   string
+	output string
+}
+
+func (s *stdinOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.from, "from", "", "convert a single migration read from stdin, skipping the folders: its format, "+
+		"goose, goose-go, sql-migrate, dbmate, go-pg, plain or auto")
+	fs.StringVar(&s.to, "to", "", "with -from, the destination library format, the same as -dst-lib")
+	fs.StringVar(&s.name, "name", "", "with -from, the source filename giving the version and name of the migration (default 1_stdin.sql)")
+	fs.StringVar(&s.output, "output", outputText, "with -from, how the destination files are printed: text, tar or json")
+}
+
+// stdinFile is a destination file of the json output.
+type stdinFile struct {
+	Name        string `json:"name"`
+	Transaction bool   `json:"transaction"`
+	Content     string `json:"content"`
+}
+
+// stdinOutput is the json output of a migration converted from stdin.
+type stdinOutput struct {
+	Format   builder.SourceFormat `json:"format"`
+	Version  int64                `json:"version"`
+	Name     string               `json:"name"`
+	Up       *stdinFile           `json:"up,omitempty"`
+	Down     *stdinFile           `json:"down,omitempty"`
+	Warnings []string             `json:"warnings,omitempty"`
+}
+
+// convertStdin converts the migration read from stdin and prints the
+// destination files to stdout.
+func convertStdin(o *convertOptions, s stdinOptions) {
+	var from builder.SourceFormat
+	if s.from != formatAuto {
+		var err error
+		if from, err = builder.GetSourceFormat(s.from); err != nil {
+			usagef("get source format error: %s", err.Error())
+		}
+	}
+	switch s.output {
+	case outputText, outputTar, outputJSON:
+	default:
+		usagef("unknown output %q, expected text, tar or json", s.output)
+	}
+	if s.to != "" {
+		o.dstType = s.to
+	}
+	name := s.name
+	if name == "" {
+		name = "1_stdin.sql"
+		if filename, ok := stdinFilenames[from]; ok {
+			name = filename
+		}
+	}
+
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fatalf("read stdin error: %s", err.Error())
+	}
+	opts := o.conversion()
+	result, err := converter.ConvertMigration(context.Background(), name, content, opts)
+	result = converted(opts, result, err)
+	if len(result.Migrations) == 0 {
+		for _, skipped := range result.Skipped {
+			fatalf("stdin isn't converted: %s", skipped.Reason)
+		}
+		fatalf("stdin isn't converted: no migration found")
+	}
+	m := result.Migrations[0]
+	// a file without annotations is a valid migration of any format
+	if from != "" && m.Format != from && m.Format != builder.SourceFormatPlain {
+		fatalf("stdin is a %s migration, not %s", m.Format, from)
+	}
+
+	switch s.output {
+	case outputText:
+		err = printFiles(os.Stdout, result.Files)
+	case outputTar:
+		ts := converter.NewTarSink(os.Stdout)
+		for _, f := range result.Files {
+			if err = ts.WriteFile(f.Name, f.Content); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = ts.Close()
+		}
+	case outputJSON:
+		err = printJSON(os.Stdout, m, result.Files)
+	}
+	if err != nil {
+		fatalf("write stdout error: %s", err.Error())
+	}
+}
+
+// printFiles prints each file after a comment line with its name.
+func printFiles(w io.Writer, files []builder.File) error {
+	for i, f := range files {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		content := string(f.Content)
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if _, err := fmt.Fprintf(w, "-- %s\n%s", f.Name, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printJSON(w io.Writer, m converter.Migration, files []builder.File) error {
+	output := stdinOutput{
+		Format:   m.Format,
+		Version:  m.Version,
+		Name:     m.Name,
+		Warnings: m.Warnings,
+	}
+	for _, f := range files {
+		file := &stdinFile{Name: f.Name, Content: string(f.Content)}
+		if strings.HasSuffix(f.Name, ".up.sql") {
+			file.Transaction = m.UpTransaction
+			output.Up = file
+		} else {
+			file.Transaction = m.DownTransaction
+			output.Down = file
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
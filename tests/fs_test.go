@@ -0,0 +1,43 @@
+package tests_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/musinit/migradaptor/builder"
+)
+
+func TestFS_BuildMigrationData(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"1-companies.sql": {Data: []byte(`-- +migrate Up
+CREATE TABLE companies (id int, title string);
+-- +migrate Down
+DROP TABLE companies;
+`)},
+		"20230102150405_companies.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE companies (id int, title string);
+-- +goose Down
+DROP TABLE companies;
+`)},
+		"20230102150405_companies_dbmate.sql": {Data: []byte(`-- migrate:up
+CREATE TABLE companies (id int, title string);
+-- migrate:down
+DROP TABLE companies;
+`)},
+	}
+	for name := range fsys {
+		t.Run(name, func(t *testing.T) {
+			lines, err := builder.ReadFSFileLines(fsys, name)
+			require.NoError(t, err)
+			upLines, downLines := builder.BuildMigrationData(lines)
+			require.Equal(t, []string{"BEGIN;\n", "CREATE TABLE companies (id int, title string);", "COMMIT;\n"}, upLines)
+			require.Equal(t, []string{"BEGIN;\n", "DROP TABLE companies;", "\nCOMMIT;\n"}, downLines)
+		})
+	}
+
+	_, err := builder.ReadFSFileLines(fsys, "missing.sql")
+	require.Error(t, err)
+}